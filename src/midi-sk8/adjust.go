@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// chanAdjust is one channel's note transpose and velocity offset
+type chanAdjust struct{ transpose, velocity int }
+
+// parseChanAdjust reads one "channel: transpose [velocity offset]" line per channel,
+// channels numbered from base as displayed, skipping blank lines
+func parseChanAdjust(text string, base uint8) (map[uint8]chanAdjust, error) {
+	m := map[uint8]chanAdjust{}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" { continue }
+		num, rest, ok := strings.Cut(line, ":")
+		if !ok { return nil, fmt.Errorf("line %d: expected \"channel: transpose [velocity]\"", i+1) }
+		ch, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil || ch < int(base) || ch > int(base)+15 { return nil, fmt.Errorf("line %d: %q is not a channel (%d-%d)", i+1, num, base, base+15) }
+		f := strings.Fields(rest)
+		if len(f) == 0 || len(f) > 2 { return nil, fmt.Errorf("line %d: expected a transpose and an optional velocity offset", i+1) }
+		var n [2]int
+		for j, tok := range f {
+			n[j], err = strconv.Atoi(tok)
+			if err != nil || n[j] < -127 || n[j] > 127 { return nil, fmt.Errorf("line %d: %q is not an offset (-127 to 127)", i+1, tok) }
+		}
+		m[uint8(ch-int(base))] = chanAdjust{n[0], n[1]}
+	}
+	return m, nil
+}
+
+// formatChanAdjust is the inverse of parseChanAdjust, ordered by channel
+func formatChanAdjust(m map[uint8]chanAdjust, base uint8) string {
+	var chs []int
+	for ch := range m { chs = append(chs, int(ch)) }
+	sort.Ints(chs)
+	var lines []string
+	for _, ch := range chs {
+		a := m[uint8(ch)]
+		line := fmt.Sprintf("%d: %+d", ch+int(base), a.transpose)
+		if a.velocity != 0 { line += fmt.Sprintf(" %+d", a.velocity) }
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func clamp7(n int) uint8 { return uint8(min(max(n, 0), 127)) }
+
+// withData is msg with its two data bytes replaced, keeping the status as it was
+func withData(msg midi.Message, d1, d2 uint8) midi.Message {
+	out := slices.Clone(msg)
+	out[1], out[2] = d1, d2
+	return out
+}
+
+// adjustNote applies the channel's transpose and velocity offset to notes and poly
+// pressure, clamped to 0-127. A note start keeps a velocity of at least 1 so it stays
+// a note start. Note ends and poly pressure follow the key their note started on, so
+// editing the offsets while a note sounds cannot leave it hanging.
+func (s *AppState) adjustNote(msg midi.Message) midi.Message {
+	var ch, key, vel uint8
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case msg.GetNoteStart(&ch, &key, &vel):
+		a, ok := s.chanAdj[ch]
+		if !ok { delete(s.shifted, noteKey{ch, key}); return msg }
+		k := clamp7(int(key) + a.transpose)
+		if s.shifted == nil { s.shifted = map[noteKey]uint8{} }
+		s.shifted[noteKey{ch, key}] = k
+		return withData(msg, k, max(clamp7(int(vel)+a.velocity), 1))
+	case msg.GetNoteEnd(&ch, &key):
+		a, ok := s.chanAdj[ch]
+		k, held := s.shifted[noteKey{ch, key}]
+		delete(s.shifted, noteKey{ch, key})
+		if !held && !ok { return msg }
+		if !held { k = clamp7(int(key) + a.transpose) }
+		// a note-on at velocity 0 has to stay at 0 to stay a note end
+		v := msg[2]
+		if v > 0 { v = clamp7(int(v) + a.velocity) }
+		return withData(msg, k, v)
+	case msg.GetPolyAfterTouch(&ch, &key, &vel):
+		a, ok := s.chanAdj[ch]
+		k, held := s.shifted[noteKey{ch, key}]
+		if !held && !ok { return msg }
+		if !held { k = clamp7(int(key) + a.transpose) }
+		return withData(msg, k, vel)
+	}
+	return msg
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestParseChanAdjust(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		base    uint8
+		want    map[uint8]chanAdjust
+		wantErr bool
+	}{
+		{"", 1, map[uint8]chanAdjust{}, false},
+		{"1: -12\n\n2: +7 +10", 1, map[uint8]chanAdjust{0: {-12, 0}, 1: {7, 10}}, false},
+		{"16: 3", 1, map[uint8]chanAdjust{15: {3, 0}}, false},
+		{"0: -12\n15: 3", 0, map[uint8]chanAdjust{0: {-12, 0}, 15: {3, 0}}, false},
+		{"0: -12", 1, nil, true},
+		{"16: 3", 0, nil, true},
+		{"1 -12", 1, nil, true},
+		{"1: 200", 1, nil, true},
+		{"1: 1 2 3", 1, nil, true},
+	} {
+		got, err := parseChanAdjust(tc.text, tc.base)
+		if (err != nil) != tc.wantErr { t.Errorf("parseChanAdjust(%q, %d) error = %v, want error %v", tc.text, tc.base, err, tc.wantErr); continue }
+		if tc.wantErr { continue }
+		if len(got) != len(tc.want) { t.Errorf("parseChanAdjust(%q, %d) = %v, want %v", tc.text, tc.base, got, tc.want); continue }
+		for ch, a := range tc.want { if got[ch] != a { t.Errorf("parseChanAdjust(%q, %d)[%d] = %v, want %v", tc.text, tc.base, ch, got[ch], a) } }
+	}
+	m := map[uint8]chanAdjust{0: {-12, 0}, 9: {7, 10}}
+	for base, want := range map[uint8]string{1: "1: -12\n10: +7 +10", 0: "0: -12\n9: +7 +10"} {
+		if got := formatChanAdjust(m, base); got != want { t.Errorf("formatChanAdjust(base %d) = %q, want %q", base, got, want) }
+	}
+}
+
+func TestAdjustNote(t *testing.T) {
+	s := &AppState{chanAdj: map[uint8]chanAdjust{0: {12, -10}}}
+	for _, tc := range []struct{ in, want midi.Message }{
+		{midi.NoteOn(0, 60, 100), midi.NoteOn(0, 72, 90)},
+		{midi.NoteOn(0, 64, 5), midi.NoteOn(0, 76, 1)}, // stays a note start
+		{midi.NoteOn(1, 60, 100), midi.NoteOn(1, 60, 100)},
+		{midi.NoteOff(0, 60), midi.NoteOff(0, 72)},
+		{midi.NoteOn(0, 64, 0), midi.NoteOn(0, 76, 0)},
+		{midi.NoteOn(0, 120, 127), midi.NoteOn(0, 127, 117)},
+	} {
+		if got := s.adjustNote(tc.in); string(got) != string(tc.want) { t.Errorf("adjustNote(%v) = %v, want %v", tc.in, got, tc.want) }
+	}
+	// a note started before the offsets changed ends on the key it started on
+	s.adjustNote(midi.NoteOn(0, 50, 100))
+	s.chanAdj = nil
+	if got := s.adjustNote(midi.NoteOff(0, 50)); string(got) != string(midi.NoteOff(0, 62)) { t.Errorf("held note ended as %v, want key 62", got) }
+}
+
+// switching the channel numbering keeps the table and the solo on the same channels
+func TestRenumber(t *testing.T) {
+	s := newAppState()
+	sel := s.newSoloSelect()
+	s.adjEntry.SetText("1: -12\n16: +3")
+	sel.SetSelected("solo: 2")
+	s.chanNumbers.SetSelected("0-15")
+	if got := s.adjEntry.Text; got != "0: -12\n15: +3" { t.Errorf("0-15 table = %q", got) }
+	if sel.Selected != "solo: 1" || s.solo != 1 { t.Errorf("0-15 solo = %q (%d), want \"solo: 1\" (1)", sel.Selected, s.solo) }
+	if sel.Options[1] != "solo: 0" || sel.Options[16] != "solo: 15" { t.Errorf("0-15 solo options = %v", sel.Options) }
+	if got := s.snapshot().Adjust; got != "1: -12\n16: +3" { t.Errorf("preset chan_adjust = %q, want 1-16 numbering", got) }
+	s.chanNumbers.SetSelected("1-16")
+	if got := s.adjEntry.Text; got != "1: -12\n16: +3" { t.Errorf("back to 1-16 table = %q", got) }
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batcher groups payloads into a single write, joined by sep, until the buffer
+// would exceed size bytes or interval has passed since the first buffered payload.
+// With both limits at zero every payload is written straight through.
+type batcher struct {
+	mu       sync.Mutex
+	w        io.WriteCloser
+	size     int
+	interval time.Duration
+	sep      []byte
+	buf      []byte
+	timer    *time.Timer
+}
+
+func newBatcher(w io.WriteCloser, size int, interval time.Duration, sep []byte) *batcher {
+	return &batcher{w: w, size: size, interval: interval, sep: sep}
+}
+
+func (b *batcher) Write(p []byte) (int, error) {
+	if b.size <= 0 && b.interval <= 0 { return b.w.Write(p) }
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.buf) > 0 && b.size > 0 && len(b.buf)+len(b.sep)+len(p) > b.size {
+		if err := b.flushLocked(); err != nil { return 0, err }
+	}
+	if len(b.buf) > 0 { b.buf = append(b.buf, b.sep...) }
+	b.buf = append(b.buf, p...)
+	if b.size > 0 && len(b.buf) >= b.size { return len(p), b.flushLocked() }
+	if b.interval > 0 && b.timer == nil { b.timer = time.AfterFunc(b.interval, func() { b.Flush() }) }
+	return len(p), nil
+}
+
+// Flush writes out anything buffered
+func (b *batcher) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked()
+}
+
+func (b *batcher) flushLocked() error {
+	if b.timer != nil { b.timer.Stop(); b.timer = nil }
+	if len(b.buf) == 0 { return nil }
+	_, err := b.w.Write(b.buf)
+	b.buf = b.buf[:0]
+	return err
+}
+
+// Close flushes pending payloads before closing the underlying writer
+func (b *batcher) Close() error {
+	err := b.Flush()
+	if cerr := b.w.Close(); err == nil { err = cerr }
+	return err
+}
+
+// parseNonNegative reads an optional setting where empty means zero
+func parseNonNegative(name, text string) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" { return 0, nil }
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 { return 0, fmt.Errorf("%s must be a whole number >= 0", name) }
+	return n, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a WriteCloser keeping every write
+type recorder struct {
+	mu     sync.Mutex
+	writes []string
+	closed bool
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock(); defer r.mu.Unlock()
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+func (r *recorder) Close() error { r.mu.Lock(); r.closed = true; r.mu.Unlock(); return nil }
+
+func (r *recorder) got() []string { r.mu.Lock(); defer r.mu.Unlock(); return slices.Clone(r.writes) }
+
+func TestBatcherSize(t *testing.T) {
+	for _, tc := range []struct {
+		size     int
+		sep      string
+		payloads []string
+		want     []string
+	}{
+		{0, "\n", []string{"a", "b"}, []string{"a", "b"}},
+		{7, "\n", []string{"aa", "bb", "cc", "dd"}, []string{"aa\nbb", "cc\ndd"}},
+		{4, "", []string{"ab", "cd", "e"}, []string{"abcd", "e"}},
+		{3, "\n", []string{"toolong", "x"}, []string{"toolong", "x"}},
+	} {
+		r := &recorder{}
+		b := newBatcher(r, tc.size, 0, []byte(tc.sep))
+		for _, p := range tc.payloads { if n, err := b.Write([]byte(p)); n != len(p) || err != nil { t.Fatalf("Write(%q) = %d, %v", p, n, err) } }
+		if err := b.Close(); err != nil { t.Fatal(err) }
+		if got := r.got(); !slices.Equal(got, tc.want) { t.Errorf("size %d: writes %q, want %q", tc.size, got, tc.want) }
+		if !r.closed { t.Errorf("size %d: Close did not close the writer", tc.size) }
+	}
+}
+
+func TestBatcherInterval(t *testing.T) {
+	r := &recorder{}
+	b := newBatcher(r, 0, 20*time.Millisecond, []byte("\n"))
+	defer b.Close()
+	b.Write([]byte("a")); b.Write([]byte("b"))
+	if got := r.got(); len(got) != 0 { t.Errorf("written before the interval: %q", got) }
+	for deadline := time.Now().Add(time.Second); len(r.got()) == 0 && time.Now().Before(deadline); { time.Sleep(5 * time.Millisecond) }
+	if got := r.got(); !slices.Equal(got, []string{"a\nb"}) { t.Errorf("after the interval: %q, want [\"a\\nb\"]", got) }
+}
+
+func TestParseNonNegative(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false}, {" 12 ", 12, false}, {"0", 0, false}, {"-1", 0, true}, {"1.5", 0, true}, {"x", 0, true},
+	} {
+		got, err := parseNonNegative("batch size", tc.text)
+		if got != tc.want || (err != nil) != tc.wantErr { t.Errorf("parseNonNegative(%q) = %d, %v", tc.text, got, err) }
+	}
+}
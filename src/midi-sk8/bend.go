@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semitones maps a 14-bit bend to -rng..+rng, reaching rng exactly at full up
+func semitones(abs uint16, rng float64) float64 {
+	b := float64(abs) - 8192
+	if b >= 0 { return b / 8191 * rng }
+	return b / 8192 * rng
+}
+
+// parseBendRange reads the bend range in semitones; blank means the usual ±2
+func parseBendRange(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" { return 2, nil }
+	r, err := strconv.ParseFloat(text, 64)
+	if err != nil || r <= 0 { return 0, fmt.Errorf("bend range: %q is not a positive number of semitones", text) }
+	return r, nil
+}
+
+// bendVars adds $b, the signed bend (-8192..8191), and $semitones to the pitch-bend
+// variables; $p stays the raw 0..16383 value
+func (s *AppState) bendVars(c uint8, abs uint16) vars {
+	vs := msgVars(c, 0, uint8(abs>>7), abs)
+	vs["b"] = float64(abs) - 8192
+	rng, err := parseBendRange(s.bendRange.Text)
+	if err != nil { rng = 2 }
+	vs["semitones"] = semitones(abs, rng)
+	return vs
+}
+
+// bendTarget returns the note a bend is sent as when bends go to the last note: the
+// most recent note-on on the channel, which may already be released
+func (s *AppState) bendTarget(ch uint8) (uint8, bool) {
+	if !s.bendNote.Checked { return 0, false }
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.lastNote[ch]
+	return key, ok
+}
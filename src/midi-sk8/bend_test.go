@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestSemitones(t *testing.T) {
+	for _, tc := range []struct {
+		abs       uint16
+		rng, want float64
+	}{
+		{0, 2, -2}, {8192, 2, 0}, {16383, 2, 2}, {4096, 2, -1}, {16383, 12, 12}, {0, 0.5, -0.5},
+	} {
+		if got := semitones(tc.abs, tc.rng); got != tc.want { t.Errorf("semitones(%d, %v) = %v, want %v", tc.abs, tc.rng, got, tc.want) }
+	}
+}
+
+func TestParseBendRange(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		want    float64
+		wantErr bool
+	}{
+		{"", 2, false}, {" 12 ", 12, false}, {"0.5", 0.5, false}, {"0", 0, true}, {"-2", 0, true}, {"x", 0, true},
+	} {
+		got, err := parseBendRange(tc.text)
+		if got != tc.want || (err != nil) != tc.wantErr { t.Errorf("parseBendRange(%q) = %v, %v", tc.text, got, err) }
+	}
+}
+
+func TestBendVarsRange(t *testing.T) {
+	s := newAppState()
+	if got := s.bendVars(0, 16383)["semitones"]; got != 2.0 { t.Errorf("default range: $semitones %v, want 2", got) }
+	s.bendRange.SetText("12")
+	if got := s.bendVars(0, 0)["semitones"]; got != -12.0 { t.Errorf("range 12: $semitones %v, want -12", got) }
+	s.bendRange.SetText("bad")
+	if got := s.bendVars(0, 0)["semitones"]; got != -2.0 { t.Errorf("invalid range: $semitones %v, want the default -2", got) }
+}
+
+// $b is the signed bend beside the raw $p
+func TestBendVarsSigned(t *testing.T) {
+	s := newAppState()
+	for _, tc := range []struct {
+		abs  uint16
+		want string
+	}{
+		{0, "0 -8192"}, {8192, "8192 0"}, {16383, "16383 8191"}, {8191, "8191 -1"},
+	} {
+		if got := s.render("pitch-bend", "$p $b", s.bendVars(0, tc.abs)); got != tc.want { t.Errorf("bend %d: render = %q, want %q", tc.abs, got, tc.want) }
+	}
+	if got := s.render("pitch-bend", "{$b/8192}", s.bendVars(0, 0)); got != "-1.0000" { t.Errorf("{$b/8192} = %q", got) }
+}
+
+func TestBendTarget(t *testing.T) {
+	s := newAppState()
+	s.trackHeld(midi.NoteOn(0, 60, 100), time.Time{})
+	if _, ok := s.bendTarget(0); ok { t.Error("bend targets a note while the mode is off") }
+	s.bendNote.SetChecked(true)
+	s.trackHeld(midi.NoteOn(0, 64, 100), time.Time{})
+	s.trackHeld(midi.NoteOn(1, 50, 100), time.Time{})
+	s.trackHeld(midi.NoteOff(0, 64), time.Time{})
+	for _, tc := range []struct {
+		ch   uint8
+		want uint8
+		ok   bool
+	}{
+		{0, 64, true}, // the latest note-on, though it has been released
+		{1, 50, true},
+		{2, 0, false},
+	} {
+		if got, ok := s.bendTarget(tc.ch); got != tc.want || ok != tc.ok { t.Errorf("bendTarget(%d) = %d, %v, want %d, %v", tc.ch, got, ok, tc.want, tc.ok) }
+	}
+}
+
+// with bends sent as the last note, a bend re-emits that note with the bend as its level
+func TestBendAsNote(t *testing.T) {
+	s := newAppState()
+	s.noteOnTpl.SetText("on n$n v$v")
+	s.pbTpl.SetText("pb $p")
+	s.bendNote.SetChecked(true)
+	if got, _, _ := s.dispatch(midi.Pitchbend(0, 0), time.Time{}); got != "pb 8192" { t.Errorf("bend before any note = %q, want the bend template", got) }
+	s.dispatch(midi.NoteOn(0, 60, 100), time.Time{})
+	for _, tc := range []struct {
+		bend int16
+		want string
+	}{
+		{-8192, "on n60 v0"}, {0, "on n60 v64"}, {8191, "on n60 v127"},
+	} {
+		if got, _, _ := s.dispatch(midi.Pitchbend(0, tc.bend), time.Time{}); got != tc.want { t.Errorf("bend %d = %q, want %q", tc.bend, got, tc.want) }
+	}
+	if got, _, _ := s.dispatch(midi.Pitchbend(1, 0), time.Time{}); got != "pb 8192" { t.Errorf("bend on another channel = %q", got) }
+}
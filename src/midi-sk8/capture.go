@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// capture collects a fixed number of incoming messages, decoded and as hex
+type capture struct {
+	want  int
+	lines []string
+}
+
+// armCapture starts a one-shot capture of the next n messages, replacing any unfinished one
+func (s *AppState) armCapture(n int) {
+	s.mu.Lock()
+	s.capture = &capture{want: n}
+	s.mu.Unlock()
+	s.appendLog(s.midiLog, fmt.Sprintf("Capturing the next %d messages", n))
+}
+
+// captureMsg adds msg to an armed capture and shows it once it is full. It is called
+// for every message, whatever the log filters and pause say.
+func (s *AppState) captureMsg(msg midi.Message, at time.Time) {
+	s.mu.Lock()
+	armed := s.capture != nil
+	s.mu.Unlock()
+	if !armed { return }
+	row := s.decodeRow(msg, at)
+	line := fmt.Sprintf("%-12s %-16s %3s %4s %4s  %s", row[0], row[1], row[2], row[3], row[4], formatBytes("hex", msg.Bytes()))
+	s.mu.Lock()
+	c := s.capture
+	if c == nil { s.mu.Unlock(); return }
+	c.lines = append(c.lines, line)
+	full := len(c.lines) >= c.want
+	if full { s.capture = nil }
+	s.mu.Unlock()
+	if full { fyne.Do(func() { showCapture(c.lines) }) }
+}
+
+// newCaptureBar is the N field and button that arm a capture
+func (s *AppState) newCaptureBar() fyne.CanvasObject {
+	n := widget.NewEntry()
+	n.SetText("16")
+	n.Validator = func(t string) error { _, err := captureCount(t); return err }
+	btn := widget.NewButtonWithIcon("capture", theme.MediaRecordIcon(), func() {
+		if c, err := captureCount(n.Text); err == nil { s.armCapture(c) }
+	})
+	btn.Importance = widget.LowImportance
+	return container.NewHBox(container.NewGridWrap(fyne.NewSize(60, n.MinSize().Height), n), btn)
+}
+
+// captureCount reads how many messages to capture
+func captureCount(text string) (int, error) {
+	c, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || c < 1 { return 0, fmt.Errorf("capture count must be a whole number >= 1") }
+	return c, nil
+}
+
+// showCapture opens a window with the captured lines and a way to save them
+func showCapture(lines []string) {
+	w := fyne.CurrentApp().NewWindow(fmt.Sprintf("capture: %d messages", len(lines)))
+	text := strings.Join(lines, "\n") + "\n"
+	view := widget.NewMultiLineEntry()
+	view.SetText(text)
+	view.TextStyle = fyne.TextStyle{Monospace: true}
+	export := widget.NewButtonWithIcon("export", theme.DocumentSaveIcon(), func() {
+		d := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil { dialog.ShowError(err, w); return }
+			if wc == nil { return }
+			defer wc.Close()
+			if _, err := wc.Write([]byte(text)); err != nil { dialog.ShowError(err, w) }
+		}, w)
+		d.SetFileName("midi-sk8-capture-" + time.Now().Format("20060102-150405") + ".txt")
+		d.SetFilter(storage.NewExtensionFileFilter([]string{".txt"}))
+		d.Show()
+	})
+	w.SetContent(container.NewBorder(nil, export, nil, nil, view))
+	w.Resize(fyne.NewSize(560, 400))
+	w.Show()
+}
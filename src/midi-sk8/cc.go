@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseCCMap reads one "controller: template" pair per line, skipping blank lines
+func parseCCMap(text string) (map[uint8]string, error) {
+	m := map[uint8]string{}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" { continue }
+		num, tpl, ok := strings.Cut(line, ":")
+		if !ok { return nil, fmt.Errorf("line %d: expected \"cc: template\"", i+1) }
+		cc, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil || cc < 0 || cc > 127 { return nil, fmt.Errorf("line %d: %q is not a controller number (0-127)", i+1, num) }
+		m[uint8(cc)] = strings.TrimSpace(tpl)
+	}
+	return m, nil
+}
+
+// formatCCMap is the inverse of parseCCMap, ordered by controller number
+func formatCCMap(m map[uint8]string) string {
+	var ccs []int
+	for cc := range m { ccs = append(ccs, int(cc)) }
+	sort.Ints(ccs)
+	var lines []string
+	for _, cc := range ccs { lines = append(lines, fmt.Sprintf("%d: %s", cc, m[uint8(cc)])) }
+	return strings.Join(lines, "\n")
+}
+
+// channelModes names the channel mode messages that share the CC range at 120-127
+var channelModes = map[uint8]string{
+	120: "all-sound-off", 121: "reset-all-controllers", 122: "local-control", 123: "all-notes-off",
+	124: "omni-off", 125: "omni-on", 126: "mono-on", 127: "poly-on",
+}
+
+// modeVars are the cc variables plus $mode, the channel mode's name
+func modeVars(ch, cc, val uint8) vars {
+	vs := msgVars(ch, cc, val, 0)
+	vs["mode"] = channelModes[cc]
+	return vs
+}
+
+// ccTemplate picks the per-controller template, falling back to the global one
+func (s *AppState) ccTemplate(cc uint8) string {
+	s.mu.Lock()
+	tpl, ok := s.ccTpls[cc]
+	s.mu.Unlock()
+	if ok { return tpl }
+	return s.ccTpl.Text
+}
+
+// parseInvert reads a comma separated list of controller numbers, with "pb" for pitch bend
+func parseInvert(text string) (map[uint8]bool, error) {
+	m := map[uint8]bool{}
+	for _, f := range strings.Split(text, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" { continue }
+		if strings.EqualFold(f, "pb") { m[pitchBendCtl] = true; continue }
+		cc, err := strconv.Atoi(f)
+		if err != nil || cc < 0 || cc > 127 { return nil, fmt.Errorf("%q is not a controller number (0-127) or pb", f) }
+		m[uint8(cc)] = true
+	}
+	return m, nil
+}
+
+// inverted reports whether values of ctl should be flipped before transform
+func (s *AppState) inverted(ctl uint8) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.invert[ctl]
+}
+
+// invert7 and invert14 flip a 7-bit or 14-bit value end to end
+func invert7(v uint8) uint8 { return 127 - v }
+func invert14(v uint16) uint16 { return 16383 - v }
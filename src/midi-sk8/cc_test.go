@@ -0,0 +1,63 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseCCMap(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		want    map[uint8]string
+		wantErr bool
+	}{
+		{"", map[uint8]string{}, false},
+		{"1: v$c m{$v/127}\n\n 7 : v$c a$v ", map[uint8]string{1: "v$c m{$v/127}", 7: "v$c a$v"}, false},
+		{"0: a\n127: b", map[uint8]string{0: "a", 127: "b"}, false},
+		{"74: f: $v", map[uint8]string{74: "f: $v"}, false},
+		{"128: x", nil, true},
+		{"x: y", nil, true},
+		{"7 v$v", nil, true},
+	} {
+		got, err := parseCCMap(tc.text)
+		if (err != nil) != tc.wantErr { t.Errorf("parseCCMap(%q) error = %v, want error %v", tc.text, err, tc.wantErr); continue }
+		if !tc.wantErr && !maps.Equal(got, tc.want) { t.Errorf("parseCCMap(%q) = %v, want %v", tc.text, got, tc.want) }
+	}
+	m := map[uint8]string{74: "f$v", 1: "m$v"}
+	text := formatCCMap(m)
+	if text != "1: m$v\n74: f$v" { t.Errorf("formatCCMap = %q", text) }
+	if back, err := parseCCMap(text); err != nil || !maps.Equal(back, m) { t.Errorf("formatCCMap does not parse back: %v, %v", back, err) }
+}
+
+func TestModeVars(t *testing.T) {
+	vs := modeVars(2, 123, 0)
+	if vs["mode"] != "all-notes-off" || vs["n"] != 123.0 || vs["c"] != 2.0 { t.Errorf("modeVars = %v", vs) }
+}
+
+func TestCCTemplate(t *testing.T) {
+	s := newAppState()
+	s.ccTpl.SetText("c$n $v")
+	s.ccMapEntry.SetText("7: vol $v")
+	if got := s.ccTemplate(7); got != "vol $v" { t.Errorf("ccTemplate(7) = %q", got) }
+	if got := s.ccTemplate(1); got != "c$n $v" { t.Errorf("ccTemplate(1) = %q, want the global template", got) }
+}
+
+func TestParseInvert(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		want    map[uint8]bool
+		wantErr bool
+	}{
+		{"", map[uint8]bool{}, false},
+		{"7, 11, pb", map[uint8]bool{7: true, 11: true, pitchBendCtl: true}, false},
+		{"PB,,0", map[uint8]bool{pitchBendCtl: true, 0: true}, false},
+		{"128", nil, true},
+		{"mod", nil, true},
+	} {
+		got, err := parseInvert(tc.text)
+		if (err != nil) != tc.wantErr { t.Errorf("parseInvert(%q) error = %v, want error %v", tc.text, err, tc.wantErr); continue }
+		if !tc.wantErr && !maps.Equal(got, tc.want) { t.Errorf("parseInvert(%q) = %v, want %v", tc.text, got, tc.want) }
+	}
+	for v, want := range map[uint8]uint8{0: 127, 127: 0, 64: 63} { if got := invert7(v); got != want { t.Errorf("invert7(%d) = %d, want %d", v, got, want) } }
+	for v, want := range map[uint16]uint16{0: 16383, 16383: 0, 8192: 8191} { if got := invert14(v); got != want { t.Errorf("invert14(%d) = %d, want %d", v, got, want) } }
+}
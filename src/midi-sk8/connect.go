@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/drivers"
+)
+
+// disconnect ends the session, waits for its goroutines, stops listening, releases held
+// notes and closes the output, so nothing from this session outlives the call
+func (s *AppState) disconnect() {
+	s.mu.Lock()
+	if s.endSession != nil { s.endSession(); s.endSession = nil }
+	stop := s.stopMidi
+	s.stopMidi = nil
+	s.mu.Unlock()
+	s.workers.Wait()
+	if stop != nil { stop() }
+	// runDelays and drainQueue have stopped, so the releases skip the output delay and
+	// the rate limit and go straight out
+	s.mu.Lock(); s.delays, s.bucket, s.queue = nil, nil, nil; s.mu.Unlock()
+	s.releaseHeld()
+	s.closeMidiOut()
+	s.closeTCP()
+	s.closeSinks()
+	if s.udpOut != nil { s.udpOut.Close(); s.udpOut = nil }
+	s.router, s.ports = nil, nil
+	s.mu.Lock(); s.inbox, s.connectedAt = nil, time.Time{}; s.mu.Unlock()
+	s.setConnState(connIdle)
+}
+
+// connectFailed reports why connecting stopped, closes whatever the attempt had opened
+// so far and marks the connection as failed
+func (s *AppState) connectFailed(err error) {
+	s.appendLog(s.midiLog, "Error: "+err.Error())
+	s.disconnect()
+	s.setConnState(connDown)
+	s.connectDone()
+}
+
+// dialTimeout reads the dial timeout setting, defaulting to three seconds
+func (s *AppState) dialTimeout() time.Duration {
+	if ms, err := parseNonNegative("dial timeout", s.dialMs.Text); err == nil && ms > 0 { return time.Duration(ms) * time.Millisecond }
+	return 3 * time.Second
+}
+
+// startConnect runs connect unless an attempt is still under way, disabling btn until
+// it has finished. Earlier log lines are kept unless clear-on-connect is set.
+func (s *AppState) startConnect(btn *widget.Button) {
+	if s.connecting || btn.Disabled() { return }
+	s.connecting, s.connectBtn = true, btn
+	btn.Disable()
+	if s.clearOnConn.Checked { s.clearLogs() }
+	s.connect()
+}
+
+// connectDone enables Connect again once an attempt has succeeded or failed. It is
+// queued on the UI goroutine, so clicks that came in while connecting are dropped.
+func (s *AppState) connectDone() {
+	fyne.Do(func() {
+		if !s.connecting { return }
+		s.connecting = false
+		if s.connectBtn != nil { s.connectBtn.Enable() }
+	})
+}
+
+// teardownAll stops everything the bridge runs: the session with its listener, outputs
+// and workers, a running script and the echo listener. Every part checks whether it
+// is running first, so tearing down twice is harmless.
+func (s *AppState) teardownAll() {
+	s.cancelScript()
+	s.disconnect()
+	if s.echoConn != nil { s.stopEcho(); s.echoBtn.SetText("listen") }
+	s.statusLabel.SetText("not connected")
+	s.appendLog(s.midiLog, "Tore everything down")
+}
+
+// reconnectAll tears everything down and brings the configured setup back up through
+// btn, restarting the echo listener if it was running
+func (s *AppState) reconnectAll(btn *widget.Button) {
+	if s.connecting || btn.Disabled() { return }
+	echo := s.echoConn != nil
+	s.teardownAll()
+	if echo { s.echoBtn.OnTapped() }
+	s.startConnect(btn)
+}
+
+// session returns the context of the current connection; goroutines started for it
+// watch Done and are registered with s.workers so disconnect can wait for them
+func (s *AppState) session() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessionCtx == nil { return context.Background() }
+	return s.sessionCtx
+}
+
+// connect (re)opens the output and the MIDI input from the current settings
+func (s *AppState) connect() {
+	s.disconnect()
+	ctx, end := context.WithCancel(context.Background())
+	s.mu.Lock(); s.sessionCtx, s.endSession = ctx, end; s.mu.Unlock()
+	s.savePrefs(s.prefs)
+	s.mu.Lock(); s.lastSent, s.mtc, s.glides = nil, mtcState{}, nil; s.mu.Unlock()
+
+	size, err := parseNonNegative("batch size", s.batchSize.Text)
+	if err != nil { s.connectFailed(err); return }
+	ms, err := parseNonNegative("batch interval", s.batchMs.Text)
+	if err != nil { s.connectFailed(err); return }
+	ports, err := parsePorts(s.typePorts.Text)
+	if err != nil { s.connectFailed(fmt.Errorf("ports: %w", err)); return }
+	var dst io.WriteCloser
+	s.udpConn = nil
+	if s.outMode.Selected == "serial" {
+		baud, err := parseBaud(s.serialBaud.Text)
+		if err != nil { s.connectFailed(err); return }
+		port, err := openSerial(s.serialPort.Text, baud, func(line string, ok bool) {
+			s.appendLog(s.midiLog, line)
+			if ok { s.setConnState(connUp) } else { s.setConnState(connDown) }
+		})
+		if err != nil { s.connectFailed(err); return }
+		dst = port
+	} else if s.outMode.Selected == "exec" {
+		cmd, err := openExec(s.execCmd.Text, s.execEach.Checked, func(line string, ok bool) {
+			s.appendLog(s.midiLog, line)
+			if ok { s.setConnState(connUp) } else { s.setConnState(connDown) }
+		}, func(line string) { s.appendLog(s.midiLog, "exec: "+line) })
+		if err != nil { s.connectFailed(err); return }
+		dst = cmd
+	} else if s.outMode.Selected == "unixgram" || s.outMode.Selected == "unix" {
+		conn, err := dialUnix(s.outMode.Selected, s.sockPath.Text, s.dialTimeout())
+		if err != nil { s.connectFailed(err); return }
+		s.udpConn, dst = conn, conn
+	} else if dest := s.addrEntry.Text + ":" + s.portEntry.Text; routed(dest) || len(ports) > 0 {
+		// connections are dialled per resolved address as messages arrive
+		s.routeTpl, s.ports = dest, ports
+		s.router = newRouter(s.resolveDest(msgVars(0, 0, 0, 0)), s.dialTimeout())
+		dst = s.router
+	} else {
+		conn, err := net.DialTimeout("udp", s.addrEntry.Text+":"+s.portEntry.Text, s.dialTimeout())
+		if err != nil { s.connectFailed(err); return }
+		s.udpConn, dst = conn, conn
+	}
+	// framed payloads already carry their own boundaries, raw ones are split by newlines
+	var sep []byte
+	if s.framing.Selected == "raw" { sep = []byte("\n") }
+	s.udpOut = newBatcher(dst, size, time.Duration(ms)*time.Millisecond, sep)
+	glideDur, glideEvery, err := s.glideSettings()
+	if err != nil { s.connectFailed(err); return }
+	s.mu.Lock(); s.glideDur = glideDur; s.mu.Unlock()
+	if glideDur > 0 { s.workers.Go(func() { s.runGlides(ctx, glideEvery) }) }
+	outDelay, jitter, err := s.delaySettings()
+	if err != nil { s.connectFailed(err); return }
+	s.mu.Lock(); s.delay, s.jitter, s.delays, s.lastDue = outDelay, jitter, nil, time.Time{}; s.mu.Unlock()
+	if outDelay > 0 || jitter > 0 {
+		q := make(chan delayed, maxDelayed)
+		s.mu.Lock(); s.delays = q; s.mu.Unlock()
+		s.workers.Go(func() { s.runDelays(ctx, q) })
+	}
+	b, err := s.newLimiter()
+	if err != nil { s.connectFailed(err); return }
+	s.mu.Lock(); s.bucket, s.queue = b, nil; s.mu.Unlock()
+	if b != nil && s.rateMode.Selected == "queue" {
+		q := make(chan queued, maxQueued)
+		s.mu.Lock(); s.queue = q; s.mu.Unlock()
+		s.workers.Go(func() { s.drainQueue(ctx.Done(), q) })
+	}
+	s.setConnState(connUp)
+	s.openSinks(ctx)
+	if err := s.openMidiOut(); err != nil { s.connectFailed(err); return }
+
+	var in drivers.In
+	virtual := false
+	if vDrv, ok := s.virtualDriver(); ok && s.virtualIn.Checked {
+		// 0. Explicitly expose a virtual port for other apps to send into
+		var err error
+		if in, err = vDrv.OpenVirtualIn(s.virtualName.Text); err != nil {
+			s.connectFailed(fmt.Errorf("creating virtual input %q: %v", s.virtualName.Text, err))
+			return
+		}
+		virtual = true
+	}
+
+	// 1. Try to find the port selected in the dropdown
+	ins := s.inPorts()
+	for i, label := range inLabels(ins) {
+		if in == nil && label == s.midiSelect.Selected {
+			in = ins[i]
+			break
+		}
+	}
+
+	// 2. Fallback: On Linux, if it's a specific name, try creating a Virtual Port
+	if in == nil {
+		problem := "Selected port not found."
+		if s.midiSelect.Selected == "" { problem = "No MIDI input selected." }
+		vDrv, ok := s.virtualDriver()
+		if !ok {
+			s.connectFailed(fmt.Errorf("%s This MIDI driver cannot create a virtual input, so please refresh and select a valid input.", problem))
+			return
+		}
+		var err error
+		if in, err = vDrv.OpenVirtualIn(s.virtualName.Text); err != nil {
+			s.connectFailed(fmt.Errorf("%s Creating virtual input %q instead failed: %v", problem, s.virtualName.Text, err))
+			return
+		}
+		virtual = true
+	}
+
+	if virtual {
+		s.appendLog(s.midiLog, fmt.Sprintf("Created virtual input %q\nListening to: %s", s.virtualName.Text, in.String()))
+	} else {
+		s.appendLog(s.midiLog, "Listening to: "+in.String())
+	}
+
+	s.mu.Lock(); s.connectedAt, s.firstMsgAt, s.sentBytes, s.sentWrites = s.clock(), time.Time{}, 0, 0; s.lfoPos, s.lfoAt = 0, s.connectedAt; s.mu.Unlock()
+	if n, _ := parseNonNegative("input buffer", s.inBuf.Text); n > 0 {
+		r := newRing(n)
+		s.mu.Lock(); s.inbox = r; s.mu.Unlock()
+		s.workers.Go(func() { s.drainInbox(ctx, r) })
+	}
+	stop, err := midi.ListenTo(in, s.onMessage, midi.UseTimeCode())
+	if err == nil { s.mu.Lock(); s.stopMidi = stop; s.mu.Unlock(); s.listening(in); s.connectDone(); return }
+	retries, _ := parseNonNegative("retries", s.retryCount.Text)
+	if retries == 0 { s.connectFailed(fmt.Errorf("listening to %s: %v", in.String(), err)); return }
+	delay := time.Duration(retryMs(s.retryMs.Text)) * time.Millisecond
+	s.workers.Go(func() { s.retryListen(ctx, in, err, retries, delay) })
+	s.connectDone()
+}
+
+// listening shows a started listener in the status line; it must run on the UI goroutine
+func (s *AppState) listening(in drivers.In) {
+	s.statusLabel.SetText(s.connectionStatus(in))
+}
+
+// maxRetryDelay caps the doubling backoff between open attempts
+const maxRetryDelay = 10 * time.Second
+
+// retryListen keeps trying to open a busy input with doubling delays until it succeeds,
+// runs out of attempts or the session ends
+func (s *AppState) retryListen(ctx context.Context, in drivers.In, err error, retries int, delay time.Duration) {
+	for attempt := 1; attempt <= retries; attempt++ {
+		s.appendLog(s.midiLog, fmt.Sprintf("Could not open %s (%v), retry %d/%d in %s", in.String(), err, attempt, retries, delay))
+		select {
+		case <-ctx.Done():
+			s.appendLog(s.midiLog, "Retry cancelled")
+			return
+		case <-time.After(delay):
+		}
+		var stop func()
+		if stop, err = midi.ListenTo(in, s.onMessage, midi.UseTimeCode()); err == nil {
+			// the session is ended under s.mu, so either disconnect sees this listener or we see the end
+			s.mu.Lock()
+			if ctx.Err() != nil { s.mu.Unlock(); stop(); return }
+			s.stopMidi = stop
+			s.mu.Unlock()
+			fyne.Do(func() { s.listening(in) })
+			s.appendLog(s.midiLog, "Listening to: "+in.String())
+			return
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+	s.appendLog(s.midiLog, fmt.Sprintf("Error: giving up on %s: %v", in.String(), err))
+}
+
+// retryMs reads the first retry delay, defaulting to half a second
+func retryMs(text string) int {
+	if ms, err := parseNonNegative("retry delay", text); err == nil && ms > 0 { return ms }
+	return 500
+}
+
+// handle transforms, sends and logs one incoming MIDI message received at now
+func (s *AppState) handle(msg midi.Message, now time.Time) {
+	s.mu.Lock(); if s.firstMsgAt.IsZero() { s.firstMsgAt = now }; s.mu.Unlock()
+	s.trackStats(msg)
+	s.captureMsg(msg, now)
+	// adjusted notes are what is held, rendered and sent; the logs keep what came in
+	in := msg
+	out, msg, retarget := s.dispatch(msg, now)
+	gliding := out != "" && retarget != nil && retarget()
+	s.flashIn()
+	var sent error
+	// a glide sends its own steps, ending on out, so only the target is logged here
+	if out != "" && !gliding { sent = s.sendMsg(out, msg) }
+	if out != "" && (sent == nil || !s.armed) { s.flashOut() }
+	s.logEvent(in, now, out, sent)
+	if !s.isPaused && s.kindOn(&s.logKinds, messageKind(msg)) {
+		s.appendLog(s.midiLog, formatBytes(s.byteMode.Selected, in.Bytes()))
+		s.addMonitorRow(s.decodeRow(in, now))
+		if out != "" && gliding { out += " (glide)" }
+		if out != "" && !s.armed { out += " (not armed)" }
+		if errors.Is(sent, errRateLimited) { out += " (rate limited)" }
+		if out != "" { s.appendLog(s.udpLog, out) }
+	}
+	s.checkTrigger(in)
+}
+
+// dispatch renders the payload for an incoming message the way it would be sent,
+// returning "" when nothing is, along with the message after channel adjustment and,
+// for controllers and bends, a glide towards the new value. It is shared by handle and
+// the dry run, so both go through adjustment, latching, MPE, curves and the clamp.
+func (s *AppState) dispatch(msg midi.Message, now time.Time) (string, midi.Message, func() bool) {
+	msg = s.adjustNote(msg)
+	msg, ignore := s.latchNote(msg)
+	var merged bool
+	var dur time.Duration
+	if !ignore { merged, dur = s.trackHeld(msg, now) }
+	var ch, key, vel, cc, val, qf, pressure uint8
+	var bend int16
+	var abs uint16
+	var out string
+	handled := false
+	var retarget func() bool
+	if s.mpeCheck.Checked && !ignore { out, handled = s.mpeTransform(msg, merged, dur) }
+	switch {
+	case handled, ignore:
+	case msg.GetNoteStart(&ch, &key, &vel): out = s.render("note-on", s.noteOnTpl.Text, s.noteVars(ch, key, vel))
+	// a note-on at velocity 0 is a release too, sent the same way as a note-off
+	case msg.GetNoteOff(&ch, &key, &vel), msg.GetNoteEnd(&ch, &key):
+		name, tpl, v := s.releaseTemplate(vel, merged)
+		out = s.render(name, tpl, s.releaseVars(ch, key, v, dur))
+	case msg.GetPitchBend(&ch, &bend, &abs):
+		if s.inverted(pitchBendCtl) { abs = invert14(abs) }
+		// the deadband is set in 7-bit steps, so scale it to the 14-bit bend range
+		if s.passDeadband(ctlKey{ch, pitchBendCtl}, int(abs), s.deadbandThreshold()*128) {
+			emit := func(v float64) string { return s.render("pitch-bend", s.pbTpl.Text, s.bendVars(ch, uint16(math.Round(v)))) }
+			// for receivers without bend, the bend sets the level of the last note instead
+			if k, ok := s.bendTarget(ch); ok {
+				emit = func(v float64) string { return s.render("note-on", s.noteOnTpl.Text, s.noteVars(ch, k, uint8(uint16(math.Round(v))>>7))) }
+			}
+			out, retarget = emit(float64(abs)), func() bool { return s.glideTo(ctlKey{ch, pitchBendCtl}, float64(abs), msg, emit) }
+		}
+	case msg.GetAfterTouch(&ch, &pressure): out = s.render("aftertouch", s.atTpl.Text, s.pressureVars(ch, 0, pressure))
+	case msg.GetPolyAfterTouch(&ch, &key, &pressure): out = s.render("poly-at", s.polyAtTpl.Text, s.pressureVars(ch, key, pressure))
+	case msg.GetMTC(&qf): out = s.mtcTransform(qf)
+	case msg.GetControlChange(&ch, &cc, &val):
+		// channel mode messages use their own template when there is one, else they are plain ccs
+		if _, ok := channelModes[cc]; ok && s.modeTpl.Text != "" {
+			out = s.render("chan-mode", s.modeTpl.Text, modeVars(ch, cc, val))
+			break
+		}
+		if s.inverted(cc) { val = invert7(val) }
+		if s.passDeadband(ctlKey{ch, cc}, int(val), s.deadbandThreshold()) {
+			name, tpl := fmt.Sprintf("cc %d", cc), s.ccTemplate(cc)
+			emit := func(v float64) string { vs := msgVars(ch, cc, 0, 0); vs["v"] = v; return s.render(name, tpl, vs) }
+			out, retarget = emit(float64(val)), func() bool { return s.glideTo(ctlKey{ch, cc}, float64(val), msg, emit) }
+		}
+	}
+	if !s.kindOn(&s.sendKinds, messageKind(msg)) || !s.soloPass(msg) { out = "" }
+	return out, msg, retarget
+}
+
+// connectionStatus describes what the bridge actually opened, for troubleshooting
+func (s *AppState) connectionStatus(in drivers.In) string {
+	drv := "none"
+	if d := s.driver(); d != nil { drv = d.String() }
+	out := "serial " + s.serialPort.Text + " @ " + s.serialBaud.Text
+	if s.router != nil { out = "udp routed by " + s.routeTpl }
+	if s.router != nil && len(s.ports) > 0 { out += ", ports " + s.typePorts.Text }
+	if s.outMode.Selected == "exec" { out = "exec " + s.execCmd.Text }
+	if s.udpConn != nil { out = fmt.Sprintf("%s %s -> %s", s.outMode.Selected, s.udpConn.LocalAddr(), s.udpConn.RemoteAddr()) }
+	s.mu.Lock()
+	for _, k := range s.sinks { if !k.main { out += " + " + k.conf.String() } }
+	s.mu.Unlock()
+	return fmt.Sprintf("driver %s | in %q | %s", drv, in.String(), out)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// disconnect returns only once the goroutines started for the session have seen it end
+func TestDisconnectEndsSession(t *testing.T) {
+	s := newAppState()
+	if s.session().Done() != nil { t.Error("session without a connection can end") }
+	ctx, end := context.WithCancel(context.Background())
+	s.sessionCtx, s.endSession = ctx, end
+	stopped := false
+	s.workers.Go(func() { <-s.session().Done(); time.Sleep(10 * time.Millisecond); stopped = true })
+	s.disconnect()
+	if ctx.Err() == nil { t.Error("disconnect left the session running") }
+	if !stopped { t.Error("disconnect returned before the session's goroutine stopped") }
+	s.disconnect()
+}
+
+// a script waiting between steps stops when the session ends, not after the wait
+func TestDisconnectStopsScript(t *testing.T) {
+	s := newAppState()
+	r := testOutput(s)
+	ctx, end := context.WithCancel(context.Background())
+	s.sessionCtx, s.endSession = ctx, end
+	s.scriptMs.SetText("0")
+	s.scriptEntry.SetText("a\n;wait 10000\nb")
+	s.runScript()
+	for deadline := time.Now().Add(time.Second); len(r.got()) == 0 && time.Now().Before(deadline); { time.Sleep(time.Millisecond) }
+	start := time.Now()
+	s.disconnect()
+	if d := time.Since(start); d > time.Second { t.Errorf("disconnect took %v to stop the script", d) }
+	if got := r.got(); len(got) != 1 || got[0] != "a" { t.Errorf("sent %q, want [a]", got) }
+	if got := s.logRingFor(s.udpLog).text(); got != "> a\n! script stopped\n" { t.Errorf("log = %q", got) }
+}
+
+// teardownAll stops every worker, the script and the echo listener, and a second
+// teardown is harmless
+func TestTeardownAll(t *testing.T) {
+	s := newAppState()
+	r := testOutput(s)
+	s.newEchoToggle(widget.NewLabel(""))
+	base := runtime.NumGoroutine()
+	ctx, end := context.WithCancel(context.Background())
+	s.sessionCtx, s.endSession = ctx, end
+	delays, queue := make(chan delayed, 1), make(chan queued, 1)
+	s.delays, s.bucket, s.queue = delays, newBucket(1, 1, time.Now()), queue
+	s.workers.Go(func() { s.runGlides(ctx, time.Millisecond) })
+	s.workers.Go(func() { s.runDelays(ctx, delays) })
+	s.workers.Go(func() { s.drainQueue(ctx.Done(), queue) })
+	s.scriptMs.SetText("0")
+	s.scriptEntry.SetText(";wait 10000\nlate")
+	s.runScript()
+	// the echo socket without its reader, whose last log line would race the test's
+	// own under the test driver, which runs fyne.Do on the calling goroutine
+	echo := listenUDP(t)
+	s.echoConn = echo
+	s.echoBtn.SetText("stop")
+	s.teardownAll()
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > base && time.Now().Before(deadline); { time.Sleep(time.Millisecond) }
+	if n := runtime.NumGoroutine(); n > base { t.Errorf("%d goroutines left running after teardown", n-base) }
+	if s.udpOut != nil || !r.closed { t.Error("output left open") }
+	echo.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, _, err := echo.ReadFrom(nil); s.echoConn != nil || !errors.Is(err, net.ErrClosed) || s.echoBtn.Text != "listen" { t.Errorf("echo left running, button %q", s.echoBtn.Text) }
+	if s.delays != nil || s.bucket != nil || s.queue != nil { t.Error("session state left behind") }
+	s.teardownAll()
+	if got := s.logRingFor(s.midiLog).text(); got != "Tore everything down (x2)\n" { t.Errorf("midi log = %q", got) }
+	if got := s.logRingFor(s.udpLog).text(); got != "! script stopped\n" || len(r.got()) != 0 { t.Errorf("script: log %q, sent %q", got, r.got()) }
+}
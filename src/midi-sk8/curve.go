@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// curves are the response shapes offered for velocity and pressure
+var curves = []string{"linear", "soft", "hard"}
+
+// applyCurve shapes a normalized 0.0-1.0 value: soft boosts light touches, hard needs more force
+func applyCurve(name string, x float64) float64 {
+	switch name {
+	case "soft": return math.Sqrt(x)
+	case "hard": return x * x
+	}
+	return x
+}
+
+// noteVars adds the normalized, curve-shaped velocity $vn (0.0-1.0) for note messages
+func (s *AppState) noteVars(c, n, v uint8) vars {
+	vs := msgVars(c, n, v, 0)
+	vs["vn"] = applyCurve(s.velCurve.Selected, float64(v)/127)
+	return vs
+}
+
+// releaseCurves are the choices for note-off velocity; the first keeps the note-on curve
+var releaseCurves = append([]string{"as note-on"}, curves...)
+
+// releaseVars are the note-off variables: the release velocity as $v and its alias $rv,
+// normalized as $vn and $rvn, shaped by the release curve, and $dur, how many
+// milliseconds the note was held
+func (s *AppState) releaseVars(c, n, v uint8, dur time.Duration) vars {
+	vs := msgVars(c, n, v, 0)
+	curve := s.relCurve.Selected
+	if curve == releaseCurves[0] { curve = s.velCurve.Selected }
+	vs["rv"] = float64(v)
+	vs["vn"] = applyCurve(curve, float64(v)/127)
+	vs["rvn"] = vs["vn"]
+	vs["dur"] = float64(dur.Milliseconds())
+	return vs
+}
+
+// pressureVars exposes raw pressure as $v and normalized pressure as $pn, shaped by the
+// velocity curve when that is enabled for pressure. Poly pressure also sets $n.
+func (s *AppState) pressureVars(c, n, p uint8) vars {
+	vs := msgVars(c, n, p, 0)
+	pn := float64(p) / 127
+	if s.curveAt.Checked { pn = applyCurve(s.velCurve.Selected, pn) }
+	vs["pn"] = pn
+	return vs
+}
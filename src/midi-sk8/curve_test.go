@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestNoteVars(t *testing.T) {
+	s := newAppState()
+	for v, want := range map[uint8]float64{0: 0, 127: 1, 100: 100.0 / 127} {
+		vs := s.noteVars(0, 60, v)
+		if vs["vn"] != want || vs["v"] != float64(v) { t.Errorf("noteVars(v %d): vn %v v %v, want vn %v", v, vs["vn"], vs["v"], want) }
+	}
+	if got := s.render("note-on", "l$vn", s.noteVars(0, 60, 127)); got != "l1" { t.Errorf("render $vn = %q", got) }
+}
+
+func TestApplyCurve(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		x, want float64
+	}{
+		{"linear", 0.25, 0.25}, {"soft", 0.25, 0.5}, {"hard", 0.5, 0.25},
+		{"soft", 0, 0}, {"hard", 1, 1}, {"unknown", 0.3, 0.3},
+	} {
+		if got := applyCurve(tc.name, tc.x); got != tc.want { t.Errorf("applyCurve(%q, %v) = %v, want %v", tc.name, tc.x, got, tc.want) }
+	}
+}
+
+func TestPressureVars(t *testing.T) {
+	s := newAppState()
+	s.velCurve.SetSelected("hard")
+	if vs := s.noteVars(0, 60, 127); vs["vn"] != 1.0 { t.Errorf("hard curve at full velocity: vn %v", vs["vn"]) }
+	vs := s.pressureVars(2, 60, 127)
+	if vs["pn"] != 1.0 || vs["n"] != 60.0 || vs["v"] != 127.0 { t.Errorf("pressureVars = %v", vs) }
+	half := uint8(64)
+	if got := s.pressureVars(0, 0, half)["pn"]; got != float64(half)/127 { t.Errorf("pressure without the curve: pn %v", got) }
+	s.curveAt.SetChecked(true)
+	if got, want := s.pressureVars(0, 0, half)["pn"], applyCurve("hard", float64(half)/127); got != want { t.Errorf("pressure with the curve: pn %v, want %v", got, want) }
+}
+
+func TestReleaseVars(t *testing.T) {
+	for _, tc := range []struct {
+		vel, rel string
+		v        uint8
+		want     float64
+	}{
+		{"linear", "as note-on", 64, 64.0 / 127},
+		{"hard", "as note-on", 64, applyCurve("hard", 64.0/127)},
+		{"hard", "linear", 64, 64.0 / 127},
+		{"linear", "soft", 64, applyCurve("soft", 64.0/127)},
+		{"soft", "hard", 127, 1},
+	} {
+		s := newAppState()
+		s.velCurve.SetSelected(tc.vel)
+		s.relCurve.SetSelected(tc.rel)
+		vs := s.releaseVars(1, 60, tc.v, 250*time.Millisecond)
+		if vs["vn"] != tc.want || vs["rvn"] != tc.want { t.Errorf("%s, release %s: vn %v rvn %v, want %v", tc.vel, tc.rel, vs["vn"], vs["rvn"], tc.want) }
+		if vs["v"] != float64(tc.v) || vs["rv"] != float64(tc.v) || vs["dur"] != 250.0 { t.Errorf("%s, release %s: v %v rv %v dur %v", tc.vel, tc.rel, vs["v"], vs["rv"], vs["dur"]) }
+	}
+}
+
+// the release velocity decoded from a note-off reaches the template
+func TestReleaseVelocity(t *testing.T) {
+	s := newAppState()
+	s.noteOffTpl.SetText("off n$n rv$rv v$v")
+	for _, tc := range []struct {
+		msg  midi.Message
+		want string
+	}{
+		{midi.NoteOffVelocity(0, 60, 90), "off n60 rv90 v90"},
+		{midi.NoteOff(0, 61), "off n61 rv0 v0"},
+		{midi.NoteOn(0, 62, 0), "off n62 rv0 v0"}, // a release too
+	} {
+		if out, _, _ := s.dispatch(tc.msg, time.Time{}); out != tc.want { t.Errorf("dispatch(%v) = %q, want %q", tc.msg, out, tc.want) }
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+// pitchBendCtl is the controller slot pitch bend uses in ctlKey
+const pitchBendCtl = 128
+
+// ctlKey identifies one continuous controller on one channel
+type ctlKey struct{ ch, ctl uint8 }
+
+// passDeadband reports whether v moved more than threshold away from the last value
+// forwarded for k, remembering it when it did. A threshold of 0 lets everything through.
+func (s *AppState) passDeadband(k ctlKey, v, threshold int) bool {
+	if threshold <= 0 { return true }
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastSent == nil { s.lastSent = map[ctlKey]int{} }
+	last, seen := s.lastSent[k]
+	if seen && v-last <= threshold && last-v <= threshold { return false }
+	s.lastSent[k] = v
+	return true
+}
+
+// deadbandThreshold reads the setting in 7-bit steps; invalid input disables it
+func (s *AppState) deadbandThreshold() int {
+	n, _ := parseNonNegative("deadband", s.deadband.Text)
+	return n
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestPassDeadband(t *testing.T) {
+	s := &AppState{}
+	k, other := ctlKey{0, 7}, ctlKey{1, 7}
+	for i, tc := range []struct {
+		k         ctlKey
+		v, thresh int
+		want      bool
+	}{
+		{k, 64, 2, true}, // first value always passes
+		{k, 66, 2, false},
+		{k, 62, 2, false},
+		{k, 67, 2, true},
+		{k, 65, 2, false}, // measured from the last value passed, 67
+		{k, 64, 2, true},
+		{other, 64, 2, true}, // per channel and controller
+		{k, 65, 0, true},     // 0 is off
+	} {
+		if got := s.passDeadband(tc.k, tc.v, tc.thresh); got != tc.want { t.Errorf("step %d: passDeadband(%v, %d, %d) = %v, want %v", i, tc.k, tc.v, tc.thresh, got, tc.want) }
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// maxDelayed bounds the payloads waiting out the output delay
+const maxDelayed = 4096
+
+// errDelayFull is returned by send when too many payloads are already waiting
+var errDelayFull = errors.New("delay queue full")
+
+// delayed is a payload framed for every sink, held back until due
+type delayed struct {
+	queued
+	due time.Time
+}
+
+// delaySettings reads the fixed output delay and the jitter range added on top
+func (s *AppState) delaySettings() (time.Duration, time.Duration, error) {
+	ms, err := parseNonNegative("output delay", s.delayMs.Text)
+	if err != nil { return 0, 0, err }
+	jitter, err := parseNonNegative("delay jitter", s.jitterMs.Text)
+	if err != nil { return 0, 0, err }
+	return time.Duration(ms) * time.Millisecond, time.Duration(jitter) * time.Millisecond, nil
+}
+
+// deliver writes a payload to every sink, or hands it to runDelays when output is
+// delayed. Jitter never reorders: a payload is never due before the one ahead of it.
+func (s *AppState) deliver(p queued) error {
+	s.mu.Lock()
+	q := s.delays
+	if q == nil { s.mu.Unlock(); return s.write(p) }
+	due := time.Now().Add(s.delay)
+	if s.jitter > 0 { due = due.Add(rand.N(s.jitter + 1)) }
+	if due.Before(s.lastDue) { due = s.lastDue }
+	s.lastDue = due
+	s.mu.Unlock()
+	select {
+	case q <- delayed{p, due}: return nil
+	default: return errDelayFull
+	}
+}
+
+// runDelays writes each delayed payload when it falls due, in the order sent, until the
+// session ends; whatever is still waiting then is discarded with the connection
+func (s *AppState) runDelays(ctx context.Context, q <-chan delayed) {
+	for {
+		var d delayed
+		select {
+		case <-ctx.Done(): return
+		case d = <-q:
+		}
+		select {
+		case <-ctx.Done(): return
+		case <-time.After(time.Until(d.due)):
+		}
+		s.write(d.queued)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestDelaySettings(t *testing.T) {
+	for _, tc := range []struct {
+		ms, jitter    string
+		delay, spread time.Duration
+		wantErr       bool
+	}{
+		{"", "", 0, 0, false},
+		{"40", "", 40 * time.Millisecond, 0, false},
+		{"40", "10", 40 * time.Millisecond, 10 * time.Millisecond, false},
+		{"-1", "", 0, 0, true},
+		{"40", "x", 0, 0, true},
+	} {
+		s := newAppState()
+		s.delayMs.SetText(tc.ms)
+		s.jitterMs.SetText(tc.jitter)
+		delay, spread, err := s.delaySettings()
+		if delay != tc.delay || spread != tc.spread || (err != nil) != tc.wantErr { t.Errorf("delay %q jitter %q = %v, %v, %v", tc.ms, tc.jitter, delay, spread, err) }
+	}
+}
+
+// testPayload is text framed for the sinks of s, as sendTo hands it on
+func testPayload(t *testing.T, s *AppState, text string) queued {
+	t.Helper()
+	out, err := s.frameAll(text)
+	if err != nil { t.Fatal(err) }
+	return queued{out: out}
+}
+
+// every payload is due at least the fixed delay after it was sent, and jitter never
+// makes it due before the one ahead
+func TestDeliverSchedule(t *testing.T) {
+	s := newAppState()
+	testOutput(s)
+	q := make(chan delayed, 200)
+	s.delays, s.delay, s.jitter = q, 20*time.Millisecond, 30*time.Millisecond
+	var sent []time.Time
+	for i := range 200 {
+		sent = append(sent, time.Now())
+		if err := s.deliver(testPayload(t, s, fmt.Sprint(i))); err != nil { t.Fatal(err) }
+	}
+	if err := s.deliver(testPayload(t, s, "x")); err != errDelayFull { t.Errorf("deliver to a full queue = %v, want %v", err, errDelayFull) }
+	var last time.Time
+	for i := range 200 {
+		d := <-q
+		if got := string(d.out[0].data); got != fmt.Sprint(i) { t.Fatalf("payload %d is %q", i, got) }
+		if d.due.Before(sent[i].Add(s.delay)) { t.Errorf("payload %d due %v after sending, under the delay", i, d.due.Sub(sent[i])) }
+		if d.due.Before(last) { t.Errorf("payload %d due before the one ahead of it", i) }
+		last = d.due
+	}
+}
+
+func TestRunDelays(t *testing.T) {
+	s := newAppState()
+	r := testOutput(s)
+	q := make(chan delayed, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { s.runDelays(ctx, q); close(done) }()
+	start := time.Now()
+	for i := range 3 { q <- delayed{testPayload(t, s, fmt.Sprint(i)), start.Add(30 * time.Millisecond)} }
+	for deadline := start.Add(time.Second); len(r.got()) < 3 && time.Now().Before(deadline); { time.Sleep(time.Millisecond) }
+	if d := time.Since(start); d < 30*time.Millisecond { t.Errorf("written after %v, before they were due", d) }
+	if got := r.got(); !slices.Equal(got, []string{"0", "1", "2"}) { t.Errorf("wrote %q, want [0 1 2]", got) }
+	q <- delayed{testPayload(t, s, "late"), time.Now().Add(time.Hour)}
+	cancel()
+	<-done
+	if got := r.got(); len(got) != 3 { t.Errorf("wrote %q after the session ended", got[3:]) }
+}
+
+// notes still sounding when the session ends are released at once rather than into a
+// delay queue nothing reads any more
+func TestDisconnectReleasesDelayed(t *testing.T) {
+	s := newAppState()
+	r := testOutput(s)
+	s.noteOffTpl.SetText("off n$n")
+	ctx, end := context.WithCancel(context.Background())
+	s.sessionCtx, s.endSession = ctx, end
+	q := make(chan delayed, 8)
+	s.delays, s.delay = q, time.Hour
+	s.workers.Go(func() { s.runDelays(ctx, q) })
+	s.dispatch(midi.NoteOn(0, 60, 100), time.Now())
+	s.disconnect()
+	if got := r.got(); !slices.Equal(got, []string{"off n60"}) { t.Errorf("sent %q, want [off n60]", got) }
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"gitlab.com/gomidi/midi/v2/drivers"
+)
+
+// driverNames lists the MIDI drivers compiled into this build; rtmidi is always
+// there, others are added with build tags (see driver_portmidi.go)
+func driverNames() []string {
+	var names []string
+	for name := range drivers.REGISTRY { names = append(names, name) }
+	sort.Strings(names)
+	return names
+}
+
+// driver returns the chosen MIDI driver, falling back to the first registered one
+func (s *AppState) driver() drivers.Driver {
+	if d, ok := drivers.REGISTRY[s.driverSel.Selected]; ok { return d }
+	return drivers.Get()
+}
+
+// inPorts lists the inputs of the chosen driver
+func (s *AppState) inPorts() []drivers.In {
+	d := s.driver()
+	if d == nil { return nil }
+	ins, err := d.Ins()
+	if err != nil { return nil }
+	return ins
+}
+
+// dupSuffix is the " #N" inLabels appends to a duplicated port name
+var dupSuffix = regexp.MustCompile(` #[0-9]+$`)
+
+// inLabels names each input for the drop-down. A name shared by several ports, such as
+// two identical controllers, gets the driver's port number appended to tell them apart.
+func inLabels(ins []drivers.In) []string {
+	count := map[string]int{}
+	for _, p := range ins { count[p.String()]++ }
+	labels := make([]string, len(ins))
+	for i, p := range ins {
+		labels[i] = p.String()
+		if count[p.String()] > 1 { labels[i] = fmt.Sprintf("%s #%d", p.String(), p.Number()) }
+	}
+	return labels
+}
+
+// selectIn re-selects the input the user picked by label, since its position in the
+// list changes when a device is re-plugged. A label whose port number has moved falls
+// back to the first port with the same name. Without a remembered label the first input
+// is picked; a remembered one that is gone leaves the selection cleared.
+func (s *AppState) selectIn(names []string) {
+	base := dupSuffix.ReplaceAllString(s.wantIn, "")
+	same := slices.IndexFunc(names, func(n string) bool { return dupSuffix.ReplaceAllString(n, "") == base })
+	switch {
+	case slices.Contains(names, s.wantIn): s.midiSelect.SetSelected(s.wantIn)
+	case s.wantIn != "" && same >= 0: s.midiSelect.SetSelected(names[same])
+	case s.wantIn != "":
+		s.midiSelect.ClearSelected()
+		s.appendLog(s.midiLog, fmt.Sprintf("MIDI input %q is not present, selection cleared", s.wantIn))
+	case len(names) > 0: s.midiSelect.SetSelected(names[0])
+	}
+}
+
+// virtualInDriver is implemented by drivers that can create their own input port
+type virtualInDriver interface{ OpenVirtualIn(string) (drivers.In, error) }
+
+func (s *AppState) virtualDriver() (virtualInDriver, bool) {
+	vDrv, ok := s.driver().(virtualInDriver)
+	return vDrv, ok
+}
+
+// driverChanged enables the virtual input options only where the driver supports them
+func (s *AppState) driverChanged() {
+	if _, ok := s.virtualDriver(); ok {
+		s.virtualIn.Enable(); s.virtualName.Enable()
+		s.virtualName.SetPlaceHolder("")
+		return
+	}
+	s.virtualIn.Disable(); s.virtualName.Disable()
+	s.virtualName.SetPlaceHolder("not supported by this MIDI driver")
+}
+
+// noPortsHint is shown in the status line while there is nothing to listen to
+const noPortsHint = "no MIDI inputs found"
+
+// checkPorts explains an empty port list. With a driver that can create a virtual input,
+// Connect falls back to one, so offer it; otherwise keep Connect disabled until a port appears.
+func (s *AppState) checkPorts(btn *widget.Button, w fyne.Window, offer bool) {
+	idle := s.statusLabel.Text == "not connected" || strings.HasPrefix(s.statusLabel.Text, noPortsHint)
+	if len(s.midiSelect.Options) > 0 {
+		btn.Enable()
+		if idle { s.statusLabel.SetText("not connected") }
+		return
+	}
+	if _, ok := s.virtualDriver(); !ok {
+		btn.Disable()
+		if idle { s.statusLabel.SetText(noPortsHint + ", and this driver cannot create a virtual one: plug in a device and refresh") }
+		return
+	}
+	btn.Enable()
+	if idle { s.statusLabel.SetText(noPortsHint + fmt.Sprintf(", Connect will create virtual input %q", s.virtualName.Text)) }
+	if !offer || s.virtualIn.Checked { return }
+	dialog.ShowConfirm("No MIDI inputs",
+		fmt.Sprintf("No MIDI input ports were found.\nCreate a virtual input %q so software senders can connect to it?", s.virtualName.Text),
+		func(ok bool) { if ok { s.virtualIn.SetChecked(true) } }, w)
+}
+
+// closeDrivers releases every registered driver, not just the default one
+func closeDrivers() {
+	for _, d := range drivers.REGISTRY { d.Close() }
+}
@@ -0,0 +1,6 @@
+//go:build portmidi
+
+package main
+
+// build with -tags portmidi to offer portmidi next to rtmidi (needs libportmidi)
+import _ "gitlab.com/gomidi/midi/v2/drivers/portmididrv"
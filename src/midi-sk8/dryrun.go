@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// dryRunSequence is the canned input for -dryrun: every message type the templates
+// cover, with bends and controllers at both ends of their range
+var dryRunSequence = []midi.Message{
+	midi.NoteOn(0, 60, 100), midi.NoteOn(0, 64, 1), midi.NoteOff(0, 60), midi.NoteOffVelocity(0, 64, 64),
+	midi.Pitchbend(0, -8192), midi.Pitchbend(0, 0), midi.Pitchbend(0, 8191),
+	midi.ControlChange(0, 1, 0), midi.ControlChange(0, 1, 127), midi.ControlChange(0, 7, 64), midi.ControlChange(0, 74, 100),
+	midi.AfterTouch(0, 90), midi.PolyAfterTouch(0, 60, 50),
+	midi.ControlChange(15, 11, 32), midi.NoteOn(15, 127, 127), midi.ControlChange(0, 121, 0), midi.ControlChange(0, 123, 0),
+}
+
+// dryRunTimecode is 01:02:03:04 at 30 fps as eight quarter-frames
+var dryRunTimecode = []uint8{0x04, 0x10, 0x23, 0x30, 0x42, 0x50, 0x61, 0x76}
+
+// cannedSequence plays the -dryrun input: the canned messages, then the timecode
+func cannedSequence(emit func(midi.Message)) error {
+	for _, msg := range dryRunSequence { emit(msg) }
+	for _, qf := range dryRunTimecode { emit(midi.MTC(qf)) }
+	return nil
+}
+
+// dryRun renders the messages source plays through a preset's templates without MIDI,
+// network or a window, printing each payload. Messages go through the same dispatch as
+// a live connection, with the settings at their defaults and time standing still, so
+// $t, $dur and $lfo are 0. It returns the process exit code: 0 when every template
+// rendered, 1 on template errors and 2 when the preset cannot be loaded or source fails.
+func dryRun(path string, source func(emit func(midi.Message)) error, out, errOut io.Writer) int {
+	data, err := os.ReadFile(path)
+	if err != nil { fmt.Fprintln(errOut, err); return 2 }
+	p, warnings, err := parsePreset(data)
+	if err != nil { fmt.Fprintf(errOut, "%s: %v\n", path, err); return 2 }
+	for _, w := range warnings { fmt.Fprintf(errOut, "%s: warning: %s\n", path, w) }
+	s := newAppState()
+	s.clock = func() time.Time { return time.Time{} }
+	// templates the preset leaves out are not sent, rather than falling back to the defaults
+	for k, e := range s.templates() { e.SetText(p.Templates[k]) }
+	s.applyPreset(p)
+	failed, label := 0, ""
+	s.renderErr = func(name string, err error) { failed++; fmt.Fprintf(errOut, "%-22s %s template: %v\n", label, name, err) }
+	err = source(func(msg midi.Message) {
+		var qf uint8
+		label = msg.String()
+		res, _, _ := s.dispatch(msg, s.clock())
+		if res == "" { return }
+		if msg.GetMTC(&qf) { s.mu.Lock(); label = "MTC " + s.mtc.last["tc"].(string); s.mu.Unlock() }
+		fmt.Fprintf(out, "%-22s -> %s\n", label, res)
+	})
+	if err != nil { fmt.Fprintln(errOut, "midi input:", err); return 2 }
+	if failed > 0 { fmt.Fprintf(errOut, "%d template error(s)\n", failed); return 1 }
+	return 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePreset(t *testing.T, json string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "p.sk8")
+	if err := os.WriteFile(path, []byte(json), 0o644); err != nil { t.Fatal(err) }
+	return path
+}
+
+func TestDryRun(t *testing.T) {
+	for _, tc := range []struct {
+		name, preset, want, wantErr string
+		code                        int
+	}{
+		{"note-on and timecode", `{"version":1,"templates":{"note-on":"v$c n$n l$v","mtc":"tc $tc"}}`,
+			"NoteOn channel: 0 key: 60 velocity: 100 -> v1 n60 l100\n" +
+				"NoteOn channel: 0 key: 64 velocity: 1 -> v1 n64 l1\n" +
+				"NoteOn channel: 15 key: 127 velocity: 127 -> v16 n127 l127\n" +
+				"MTC 01:02:03:04        -> tc 01:02:03:04\n", "", 0},
+		{"channel modes fall back to cc", `{"version":1,"templates":{"cc":"c$n $v"},"cc_map":{"7":"vol $v"}}`,
+			"ControlChange channel: 0 controller: 1 value: 0 -> c1 0\n" +
+				"ControlChange channel: 0 controller: 1 value: 127 -> c1 127\n" +
+				"ControlChange channel: 0 controller: 7 value: 64 -> vol 64\n" +
+				"ControlChange channel: 0 controller: 74 value: 100 -> c74 100\n" +
+				"ControlChange channel: 15 controller: 11 value: 32 -> c11 32\n" +
+				"ControlChange channel: 0 controller: 121 value: 0 -> c121 0\n" +
+				"ControlChange channel: 0 controller: 123 value: 0 -> c123 0\n", "", 0},
+		{"inverted bend", `{"version":1,"templates":{"pitch-bend":"p$b"},"invert":"pb"}`,
+			"PitchBend channel: 0 pitch: -8192 (0) -> p8191\n" +
+				"PitchBend channel: 0 pitch: 0 (8192) -> p-1\n" +
+				"PitchBend channel: 0 pitch: 8191 (16383) -> p-8192\n", "", 0},
+		{"channel adjust", `{"version":1,"templates":{"note-on":"n$n l$v","note-off":"off n$n"},"chan_adjust":"1: +12 +10\n16: -7"}`,
+			"NoteOn channel: 0 key: 60 velocity: 100 -> n72 l110\n" +
+				"NoteOn channel: 0 key: 64 velocity: 1 -> n76 l11\n" +
+				"NoteOff channel: 0 key: 60 -> off n72\n" +
+				"NoteOff channel: 0 key: 64 velocity: 64 -> off n76\n" +
+				"NoteOn channel: 15 key: 127 velocity: 127 -> n120 l127\n", "", 0},
+		{"template error", `{"version":1,"templates":{"aftertouch":"z{$v/0}"}}`,
+			"AfterTouch channel: 0 pressure: 90 -> zDIV0\n", "aftertouch template", 1},
+		{"bad preset", `{"templates":{}}`, "", "no version", 2},
+	} {
+		var out, errOut bytes.Buffer
+		code := dryRun(writePreset(t, tc.preset), cannedSequence, &out, &errOut)
+		if code != tc.code { t.Errorf("%s: exit code %d, want %d (%s)", tc.name, code, tc.code, errOut.String()) }
+		if out.String() != tc.want { t.Errorf("%s: output\n%s\nwant\n%s", tc.name, out.String(), tc.want) }
+		if tc.wantErr != "" && !strings.Contains(errOut.String(), tc.wantErr) { t.Errorf("%s: errors %q, want %q", tc.name, errOut.String(), tc.wantErr) }
+	}
+}
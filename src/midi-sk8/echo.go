@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// startEcho binds a local UDP port and logs every datagram that arrives, for loopback
+// checks, matching each with the payload sent to report the round trip
+func (s *AppState) startEcho() error {
+	port, err := strconv.Atoi(strings.TrimSpace(s.echoPort.Text))
+	if err != nil || port < 1 || port > 65535 { return fmt.Errorf("echo port: %q is not a port number (1-65535)", s.echoPort.Text) }
+	pc, err := net.ListenPacket("udp", fmt.Sprintf(":%d", port))
+	if err != nil { return err }
+	s.echoConn = pc
+	s.mu.Lock(); s.rtt, s.echoing = &roundTrip{}, true; s.mu.Unlock()
+	s.appendLog(s.echoLog, "listening on "+pc.LocalAddr().String())
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil { s.appendLog(s.echoLog, "stopped"); return }
+			s.appendLog(s.echoLog, fmt.Sprintf("%s: %q", from, buf[:n])+s.echoed(buf[:n], time.Now()))
+		}
+	}()
+	return nil
+}
+
+// stopEcho closes the echo socket, which ends its reader; the round-trip figures are
+// kept for the stats window
+func (s *AppState) stopEcho() {
+	s.mu.Lock(); s.echoing = false; s.mu.Unlock()
+	if s.echoConn != nil { s.echoConn.Close(); s.echoConn = nil }
+}
+
+// newEchoToggle builds the start/stop button, showing pane while the echo listener runs
+func (s *AppState) newEchoToggle(pane fyne.CanvasObject) *widget.Button {
+	var btn *widget.Button
+	btn = widget.NewButton("listen", func() {
+		if s.echoConn != nil { s.stopEcho(); btn.SetText("listen"); return }
+		if err := s.startEcho(); err != nil { s.appendLog(s.midiLog, "Error: "+err.Error()); return }
+		btn.SetText("stop")
+		pane.Show()
+	})
+	s.echoBtn = btn
+	return btn
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// envEntries maps the environment variables read at startup to the entries they set:
+// SK8_ADDR, SK8_PORT and one SK8_TPL_* per template, e.g. SK8_TPL_NOTE_ON
+func (s *AppState) envEntries() map[string]*widget.Entry {
+	m := map[string]*widget.Entry{"SK8_ADDR": s.addrEntry, "SK8_PORT": s.portEntry}
+	for k, e := range s.templates() { m["SK8_TPL_"+strings.ToUpper(strings.ReplaceAll(k, "-", "_"))] = e }
+	return m
+}
+
+// applyEnv overrides the saved settings with any SK8_* variables that are set, and
+// returns the names it used. It runs after loadPrefs and before flags are applied, so
+// flags beat the environment, which beats saved preferences, which beat defaults.
+// SK8_MIDI_PORT names the MIDI input; SK8_DRIVER is read alongside -driver in main.
+func (s *AppState) applyEnv(lookup func(string) (string, bool)) []string {
+	var used []string
+	for name, e := range s.envEntries() {
+		if v, ok := lookup(name); ok { e.SetText(v); used = append(used, name) }
+	}
+	if v, ok := lookup("SK8_MIDI_PORT"); ok { s.wantIn = v; used = append(used, "SK8_MIDI_PORT") }
+	sort.Strings(used)
+	return used
+}
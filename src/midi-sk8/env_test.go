@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{"SK8_PORT": "9100", "SK8_TPL_NOTE_ON": "env $n", "SK8_MIDI_PORT": "Keys", "SK8_OTHER": "x"}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	p := test.NewTempApp(t).Preferences()
+	p.SetString("udp-addr", "10.0.0.2")
+	p.SetString("udp-port", "9001")
+	s := newAppState()
+	defAddr := s.addrEntry.Text
+	s.loadPrefs(p)
+	if s.addrEntry.Text == defAddr { t.Fatal("saved address was not loaded") }
+	used := s.applyEnv(lookup)
+	if want := []string{"SK8_MIDI_PORT", "SK8_PORT", "SK8_TPL_NOTE_ON"}; !slices.Equal(used, want) { t.Errorf("used %v, want %v", used, want) }
+	// the environment beats saved prefs, which beat defaults
+	for _, tc := range []struct{ name, got, want string }{
+		{"address", s.addrEntry.Text, "10.0.0.2"},
+		{"port", s.portEntry.Text, "9100"},
+		{"note-on", s.noteOnTpl.Text, "env $n"},
+		{"midi in", s.wantIn, "Keys"},
+	} {
+		if tc.got != tc.want { t.Errorf("%s = %q, want %q", tc.name, tc.got, tc.want) }
+	}
+	if used := newAppState().applyEnv(func(string) (string, bool) { return "", false }); len(used) != 0 { t.Errorf("empty environment used %v", used) }
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// execStop is how long a closed command gets to exit after its stdin closes
+const execStop = time.Second
+
+// execOut feeds payloads to an external command. By default one process is kept
+// running and each payload becomes a line on its stdin; if it dies it is restarted on
+// the next write, at most once per serialRetry. With perMessage every payload starts
+// its own process instead. stderr lines go to logErr, exits and restarts to log.
+type execOut struct {
+	mu         sync.Mutex
+	command    string
+	perMessage bool
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	exited     chan struct{}
+	closed     bool
+	lastTry    time.Time
+	log        func(line string, ok bool)
+	logErr     func(line string)
+}
+
+// shellCommand runs command through the platform shell so pipes and quoting work
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" { return exec.Command("cmd", "/C", command) }
+	return exec.Command("sh", "-c", command)
+}
+
+// openExec starts the command up front, unless it runs per message, so a bad command
+// is reported at connect time
+func openExec(command string, perMessage bool, log func(line string, ok bool), logErr func(line string)) (*execOut, error) {
+	if strings.TrimSpace(command) == "" { return nil, fmt.Errorf("no command set for exec output") }
+	o := &execOut{command: command, perMessage: perMessage, log: log, logErr: logErr}
+	if perMessage { return o, nil }
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o, o.startLocked()
+}
+
+// pipeStderr forwards each stderr line of a started command
+func (o *execOut) pipeStderr(cmd *exec.Cmd) (func(), error) {
+	r, err := cmd.StderrPipe()
+	if err != nil { return nil, err }
+	return func() {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() { o.logErr(sc.Text()) }
+	}, nil
+}
+
+func (o *execOut) startLocked() error {
+	cmd := shellCommand(o.command)
+	stdin, err := cmd.StdinPipe()
+	if err != nil { return err }
+	stderr, err := o.pipeStderr(cmd)
+	if err != nil { return err }
+	if err := cmd.Start(); err != nil { return fmt.Errorf("exec %q: %w", o.command, err) }
+	exited := make(chan struct{})
+	o.cmd, o.stdin, o.exited = cmd, stdin, exited
+	go func() {
+		// stderr has to be drained before Wait, which closes the pipe
+		stderr()
+		err := cmd.Wait()
+		close(exited)
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.cmd != cmd { return }
+		o.cmd, o.stdin, o.lastTry = nil, nil, time.Now()
+		if !o.closed { o.log(fmt.Sprintf("Command %q exited (%v), restarting on the next message", o.command, exitReason(err)), false) }
+	}()
+	return nil
+}
+
+// exitReason describes how a command ended
+func exitReason(err error) string {
+	if err == nil { return "status 0" }
+	return err.Error()
+}
+
+func (o *execOut) Write(p []byte) (int, error) {
+	if o.perMessage { return len(p), o.runOnce(p) }
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cmd == nil {
+		if time.Since(o.lastTry) < serialRetry { return 0, fmt.Errorf("command %q is not running", o.command) }
+		o.lastTry = time.Now()
+		if err := o.startLocked(); err != nil { return 0, err }
+		o.log(fmt.Sprintf("Restarted command %q", o.command), true)
+	}
+	line := p
+	if !bytes.HasSuffix(line, []byte("\n")) { line = append(line[:len(line):len(line)], '\n') }
+	if _, err := o.stdin.Write(line); err != nil { return 0, err }
+	return len(p), nil
+}
+
+// runOnce starts the command with p as its whole stdin and reaps it in the background
+func (o *execOut) runOnce(p []byte) error {
+	cmd := shellCommand(o.command)
+	cmd.Stdin = bytes.NewReader(p)
+	stderr, err := o.pipeStderr(cmd)
+	if err != nil { return err }
+	if err := cmd.Start(); err != nil { return fmt.Errorf("exec %q: %w", o.command, err) }
+	go func() {
+		stderr()
+		if err := cmd.Wait(); err != nil { o.logErr(fmt.Sprintf("command %q: %v", o.command, err)) }
+	}()
+	return nil
+}
+
+// Close ends stdin so the command can finish, killing it if it does not exit promptly
+func (o *execOut) Close() error {
+	o.mu.Lock()
+	o.closed = true
+	cmd, stdin, exited := o.cmd, o.stdin, o.exited
+	o.mu.Unlock()
+	if cmd == nil { return nil }
+	stdin.Close()
+	select {
+	case <-exited:
+	case <-time.After(execStop): cmd.Process.Kill(); <-exited
+	}
+	return nil
+}
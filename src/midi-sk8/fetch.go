@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// fetchTimeout bounds a whole preset download
+const fetchTimeout = 10 * time.Second
+
+// maxPresetSize is the largest preset accepted from a URL; real ones are a few KB
+const maxPresetSize = 1 << 20
+
+// fetchPreset downloads a preset over HTTPS, refusing anything too large to be one.
+// Validation is left to importPreset, as for files.
+func fetchPreset(link string) ([]byte, error) {
+	u, err := url.Parse(link)
+	if err != nil { return nil, err }
+	if u.Scheme != "https" { return nil, fmt.Errorf("only https:// URLs can be imported") }
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil { return nil, err }
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil { return nil, err }
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK { return nil, fmt.Errorf("server returned %s", resp.Status) }
+	if resp.ContentLength > maxPresetSize { return nil, fmt.Errorf("response of %d bytes is too large for a preset", resp.ContentLength) }
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPresetSize+1))
+	if err != nil { return nil, err }
+	if len(data) > maxPresetSize { return nil, fmt.Errorf("response is larger than %d bytes, too large for a preset", maxPresetSize) }
+	return data, nil
+}
+
+// showFetch asks for a preset URL and loads it in the background, logging progress
+func (s *AppState) showFetch(w fyne.Window) {
+	e := widget.NewEntry()
+	e.SetPlaceHolder("https://example.com/mapping.sk8")
+	dialog.ShowForm("Import preset from URL", "Fetch", "Cancel", []*widget.FormItem{widget.NewFormItem("url", e)}, func(ok bool) {
+		link := strings.TrimSpace(e.Text)
+		if !ok || link == "" { return }
+		s.appendLog(s.midiLog, "Fetching preset "+link)
+		go func() {
+			data, err := fetchPreset(link)
+			fyne.Do(func() {
+				if err != nil {
+					s.appendLog(s.midiLog, fmt.Sprintf("Error: preset %s: %v", link, err))
+					dialog.ShowError(err, w)
+					return
+				}
+				s.importPreset(bytes.NewReader(data), link, w)
+				// there is no file to save back to
+				s.presetURI = nil
+			})
+		}()
+	}, w)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// framings are the per-payload wire formats offered in settings
+var framings = []string{"raw", "newline", "crlf", "null", "length-prefix"}
+
+// frame wraps one payload for the wire. length-prefix is a 2-byte big-endian length.
+// Every send goes through here for each output, typed or rendered, so both are framed alike.
+func frame(mode string, p []byte) ([]byte, error) {
+	switch mode {
+	case "newline": return append(p, '\n'), nil
+	case "crlf": return append(p, '\r', '\n'), nil
+	case "null": return append(p, 0), nil
+	case "length-prefix":
+		if len(p) > 0xFFFF { return nil, fmt.Errorf("payload of %d bytes is too long for a 2-byte length prefix", len(p)) }
+		return append(binary.BigEndian.AppendUint16(make([]byte, 0, len(p)+2), uint16(len(p))), p...), nil
+	}
+	return p, nil
+}
+
+// encodings are how payload text is put on the wire: as is, or with anything outside
+// ASCII escaped as \uXXXX or dropped, for receivers that choke on other bytes
+var encodings = []string{"utf-8", "ascii escape", "ascii strip"}
+
+// encode applies an output encoding to one payload
+func encode(mode, p string) string {
+	if mode == "utf-8" || mode == "" { return p }
+	var b strings.Builder
+	for _, r := range p {
+		switch {
+		case r < utf8.RuneSelf: b.WriteRune(r)
+		case mode == "ascii strip":
+		case r > 0xFFFF: fmt.Fprintf(&b, "\\U%08x", r)
+		default: fmt.Fprintf(&b, "\\u%04x", r)
+		}
+	}
+	return b.String()
+}
+
+// errDisarmed is returned by send while output is disarmed
+var errDisarmed = errors.New("output not armed")
+
+// send frames and writes one payload to the active output
+func (s *AppState) send(payload string) error { return s.sendTo(payload, "") }
+
+// sendMsg sends a payload rendered for msg, routed by the message when the address is templated
+func (s *AppState) sendMsg(payload string, msg midi.Message) error {
+	dest := ""
+	if s.router != nil { dest = s.destination(msg) }
+	return s.sendTo(payload, dest)
+}
+
+// sendTo writes to a routed destination, or to the configured output when dest is empty
+func (s *AppState) sendTo(payload, dest string) error {
+	if s.udpOut == nil { return fmt.Errorf("not connected") }
+	if !s.armed { return errDisarmed }
+	if strings.HasPrefix(payload, sysexPrefix) {
+		err := s.sendSysEx(payload)
+		if err == nil { s.mu.Lock(); s.lastPayload = payload; s.mu.Unlock() }
+		return err
+	}
+	payload = encode(s.encoding.Selected, payload)
+	out, err := s.frameAll(s.tagged(payload))
+	if err != nil { return err }
+	q := queued{out, dest}
+	now, err := s.limit(q)
+	if err != nil { return err }
+	if now { err = s.deliver(q) }
+	s.mu.Lock(); s.lastPayload = payload; s.mu.Unlock()
+	return err
+}
+
+// write hands a payload that is through the rate limit and the output delay to every
+// sink: in line to the main output, whose error it returns, and to the queue of each
+// extra output, where a full queue drops it
+func (s *AppState) write(q queued) error {
+	var err error
+	for _, f := range q.out {
+		if f.to.main { err = s.writeMain(f.to, f.data, q.dest); continue }
+		select {
+		case f.to.queue <- f.data:
+		default:
+		}
+	}
+	return err
+}
+
+// writeMain puts one framed payload on the main output, counting what got there, and
+// updates the connection dot
+func (s *AppState) writeMain(k *sink, data []byte, dest string) error {
+	if limit := s.maxDatagram(); s.datagrams() && len(data) > limit {
+		err := s.oversize(k, data, dest, limit)
+		if err == nil { s.countSent(len(data)) }
+		return err
+	}
+	var err error
+	if dest != "" && s.router != nil { _, err = s.router.WriteTo(data, dest) } else { _, err = s.udpOut.Write(data) }
+	if err != nil { s.setConnState(connDown); return err }
+	s.setConnState(connUp)
+	s.countSent(len(data))
+	s.noteSent(data)
+	return nil
+}
+
+// repeatLast resends the most recent payload, automatic or manual
+func (s *AppState) repeatLast() {
+	s.mu.Lock()
+	p := s.lastPayload
+	s.mu.Unlock()
+	if p == "" { return }
+	if err := s.send(p); err != nil { s.appendLog(s.udpLog, "! repeat: "+err.Error()); return }
+	s.appendLog(s.udpLog, "> "+p+" (repeat)")
+	s.flashOut()
+}
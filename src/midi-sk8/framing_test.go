@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	for _, tc := range []struct {
+		mode, p, want string
+	}{
+		{"raw", "v1 n60", "v1 n60"},
+		{"", "v1 n60", "v1 n60"},
+		{"newline", "v1 n60", "v1 n60\n"},
+		{"crlf", "v1 n60", "v1 n60\r\n"},
+		{"null", "v1 n60", "v1 n60\x00"},
+		{"length-prefix", "v1 n60", "\x00\x06v1 n60"},
+		{"length-prefix", "", "\x00\x00"},
+	} {
+		got, err := frame(tc.mode, []byte(tc.p))
+		if err != nil || string(got) != tc.want { t.Errorf("frame(%q, %q) = %q, %v, want %q", tc.mode, tc.p, got, err, tc.want) }
+	}
+	if _, err := frame("length-prefix", bytes.Repeat([]byte("x"), 0x10000)); err == nil { t.Error("frame accepted a payload too long for its length prefix") }
+}
+
+// typed and rendered payloads go out framed alike
+func TestSendFramed(t *testing.T) {
+	s := newAppState()
+	s.framing.SetSelected("null")
+	out := testOutput(s)
+	if err := s.send("manual"); err != nil { t.Fatal(err) }
+	if err := s.sendMsg("v1 n60", nil); err != nil { t.Fatal(err) }
+	if got, want := out.got(), []string{"manual\x00", "v1 n60\x00"}; !slices.Equal(got, want) { t.Errorf("sent %q, want %q", got, want) }
+	for _, mode := range framings {
+		m := newAppState()
+		m.framing.SetSelected(mode)
+		mout := testOutput(m)
+		m.send("v1 n60")
+		m.sendMsg("v1 n60", nil)
+		if got := mout.got(); len(got) != 2 || got[0] != got[1] { t.Errorf("%s: manual and rendered sends went out as %q", mode, got) }
+	}
+	s.armed = false
+	if err := s.send("x"); err != errDisarmed { t.Errorf("send while disarmed = %v, want %v", err, errDisarmed) }
+	s.udpOut = nil
+	if err := s.send("x"); err == nil || !strings.Contains(err.Error(), "not connected") { t.Errorf("send without output = %v", err) }
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range []struct {
+		mode, p, want string
+	}{
+		{"utf-8", "n60 ♯ é", "n60 ♯ é"},
+		{"", "n60 ♯", "n60 ♯"},
+		{"ascii escape", "n60 ♯ é", "n60 \\u266f \\u00e9"},
+		{"ascii escape", "🎹", "\\U0001f3b9"},
+		{"ascii strip", "n60 ♯ é🎹!", "n60  !"},
+		{"ascii strip", "plain", "plain"},
+	} {
+		if got := encode(tc.mode, tc.p); got != tc.want { t.Errorf("encode(%q, %q) = %q, want %q", tc.mode, tc.p, got, tc.want) }
+	}
+}
+
+// a template with a non-ASCII character goes out encoded as chosen
+func TestSendEncoded(t *testing.T) {
+	for _, tc := range []struct{ mode, want string }{
+		{"utf-8", "C♯4 v100"}, {"ascii escape", "C\\u266f4 v100"}, {"ascii strip", "C4 v100"},
+	} {
+		s := newAppState()
+		out := testOutput(s)
+		s.encoding.SetSelected(tc.mode)
+		p := s.render("note-on", "C♯4 v$v", msgVars(0, 61, 100, 0))
+		if err := s.sendMsg(p, nil); err != nil { t.Fatal(err) }
+		if got := out.got(); len(got) != 1 || got[0] != tc.want { t.Errorf("%s: sent %q, want %q", tc.mode, got, tc.want) }
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// glide moves one controller from where it was towards the latest received value
+type glide struct {
+	from, to, pos float64
+	at            time.Time
+	msg           midi.Message
+	emit          func(v float64) string
+}
+
+// glideStep is the linear position between from and to after elapsed of a glide lasting dur
+func glideStep(from, to float64, elapsed, dur time.Duration) float64 {
+	if dur <= 0 || elapsed >= dur { return to }
+	if elapsed <= 0 { return from }
+	return from + (to-from)*float64(elapsed)/float64(dur)
+}
+
+// glideSettings reads the glide time and update rate; a zero time turns gliding off
+func (s *AppState) glideSettings() (time.Duration, time.Duration, error) {
+	ms, err := parseNonNegative("glide time", s.glideMs.Text)
+	if err != nil || ms == 0 { return 0, 0, err }
+	hz, err := parseNonNegative("glide rate", s.glideHz.Text)
+	if err != nil { return 0, 0, err }
+	if hz == 0 { hz = 60 }
+	return time.Duration(ms) * time.Millisecond, time.Second / time.Duration(hz), nil
+}
+
+// glideTo retargets the glide for k and reports whether the ticker will send it. The
+// first value of a controller is never glided, since there is nothing to start from.
+// emit renders the payload for a position.
+func (s *AppState) glideTo(k ctlKey, v float64, msg midi.Message, emit func(float64) string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.glideDur == 0 { return false }
+	if s.glides == nil { s.glides = map[ctlKey]*glide{} }
+	g, ok := s.glides[k]
+	if !ok { s.glides[k] = &glide{from: v, to: v, pos: v}; return false }
+	g.from, g.to, g.at, g.msg, g.emit = g.pos, v, time.Now(), msg, emit
+	return true
+}
+
+// runGlides sends the in-between positions of every moving controller each tick
+// until the session ends
+func (s *AppState) runGlides(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	type step struct {
+		g   *glide
+		pos float64
+	}
+	for {
+		select {
+		case <-ctx.Done(): return
+		case now := <-tick.C:
+			var steps []step
+			s.mu.Lock()
+			for _, g := range s.glides {
+				if g.pos == g.to || g.emit == nil { continue }
+				g.pos = glideStep(g.from, g.to, now.Sub(g.at), s.glideDur)
+				steps = append(steps, step{g, g.pos})
+			}
+			s.mu.Unlock()
+			for _, st := range steps {
+				if out := st.g.emit(st.pos); out != "" && s.sendMsg(out, st.g.msg) == nil { s.flashOut() }
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGlideStep(t *testing.T) {
+	ms := time.Millisecond
+	for _, tc := range []struct {
+		from, to     float64
+		elapsed, dur time.Duration
+		want         float64
+	}{
+		{0, 100, 0, 100 * ms, 0},
+		{0, 100, 25 * ms, 100 * ms, 25},
+		{0, 100, 50 * ms, 100 * ms, 50},
+		{100, 0, 25 * ms, 100 * ms, 75},
+		{0, 100, 100 * ms, 100 * ms, 100},
+		{0, 100, time.Second, 100 * ms, 100},
+		{0, 100, -ms, 100 * ms, 0},
+		{0, 100, 10 * ms, 0, 100}, // no glide time jumps straight there
+	} {
+		if got := glideStep(tc.from, tc.to, tc.elapsed, tc.dur); got != tc.want { t.Errorf("glideStep(%v, %v, %v, %v) = %v, want %v", tc.from, tc.to, tc.elapsed, tc.dur, got, tc.want) }
+	}
+}
+
+func TestGlideSettings(t *testing.T) {
+	for _, tc := range []struct {
+		ms, hz     string
+		dur, every time.Duration
+		wantErr    bool
+	}{
+		{"0", "30", 0, 0, false},
+		{"200", "", 200 * time.Millisecond, time.Second / 60, false},
+		{"200", "50", 200 * time.Millisecond, 20 * time.Millisecond, false},
+		{"x", "50", 0, 0, true},
+		{"200", "-1", 0, 0, true},
+	} {
+		s := newAppState()
+		s.glideMs.SetText(tc.ms)
+		s.glideHz.SetText(tc.hz)
+		dur, every, err := s.glideSettings()
+		if dur != tc.dur || every != tc.every || (err != nil) != tc.wantErr { t.Errorf("glide %q at %q = %v, %v, %v", tc.ms, tc.hz, dur, every, err) }
+	}
+}
+
+// the first value of a controller goes out as is; later ones glide from wherever the
+// last glide had got to
+func TestGlideTo(t *testing.T) {
+	s := newAppState()
+	k := ctlKey{0, 7}
+	emit := func(float64) string { return "" }
+	if s.glideTo(k, 10, nil, emit) { t.Error("glided while glide time is 0") }
+	s.glideDur = 100 * time.Millisecond
+	if s.glideTo(k, 10, nil, emit) { t.Error("glided the first value") }
+	if !s.glideTo(k, 90, nil, emit) { t.Error("did not glide the second value") }
+	s.glides[k].pos = 50
+	s.glideTo(k, 0, nil, emit)
+	if g := s.glides[k]; g.from != 50 || g.to != 0 { t.Errorf("retarget from %v to %v, want 50 to 0", g.from, g.to) }
+	if s.glideTo(ctlKey{1, 7}, 10, nil, emit) { t.Error("glided the first value of another channel") }
+}
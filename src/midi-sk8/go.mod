@@ -5,6 +5,7 @@ go 1.25.5
 require (
 	fyne.io/fyne/v2 v2.7.1
 	gitlab.com/gomidi/midi/v2 v2.0.30
+	go.bug.st/serial v1.8.0
 )
 
 require (
@@ -37,7 +38,7 @@ require (
 	github.com/yuin/goldmark v1.7.8 // indirect
 	golang.org/x/image v0.24.0 // indirect
 	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/sys v0.43.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
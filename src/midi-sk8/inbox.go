@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// inEvent is one MIDI message with the time the driver delivered it
+type inEvent struct {
+	msg midi.Message
+	at  time.Time
+}
+
+// ring is a bounded FIFO between the driver callback and the consumer; when full the
+// oldest message is dropped so the newest input is never blocked
+type ring struct {
+	mu      sync.Mutex
+	buf     []inEvent
+	head, n int
+	ready   chan struct{}
+}
+
+func newRing(size int) *ring { return &ring{buf: make([]inEvent, size), ready: make(chan struct{}, 1)} }
+
+// push adds e, reporting whether the oldest entry had to be dropped for it
+func (r *ring) push(e inEvent) (dropped bool) {
+	r.mu.Lock()
+	if r.n == len(r.buf) { r.head, r.n, dropped = (r.head+1)%len(r.buf), r.n-1, true }
+	r.buf[(r.head+r.n)%len(r.buf)] = e
+	r.n++
+	r.mu.Unlock()
+	select {
+	case r.ready <- struct{}{}:
+	default:
+	}
+	return dropped
+}
+
+// pop takes the oldest entry
+func (r *ring) pop() (inEvent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n == 0 { return inEvent{}, false }
+	e := r.buf[r.head]
+	r.buf[r.head] = inEvent{}
+	r.head, r.n = (r.head+1)%len(r.buf), r.n-1
+	return e, true
+}
+
+// onMessage is the driver callback. With an input buffer it only queues the message,
+// so slow UI or network work never holds up the driver; otherwise it handles it inline.
+func (s *AppState) onMessage(msg midi.Message, ts int32) {
+	now := time.Now()
+	s.mu.Lock()
+	r := s.inbox
+	s.mu.Unlock()
+	if r == nil { s.handle(msg, now); return }
+	// the driver may reuse its buffer once the callback returns
+	if r.push(inEvent{append(midi.Message(nil), msg...), now}) { s.mu.Lock(); s.inDropped++; s.mu.Unlock() }
+}
+
+// drainInbox handles queued messages in arrival order until the session ends
+func (s *AppState) drainInbox(ctx context.Context, r *ring) {
+	for {
+		select {
+		case <-ctx.Done(): return
+		case <-r.ready:
+		}
+		for e, ok := r.pop(); ok; e, ok = r.pop() {
+			if ctx.Err() != nil { return }
+			s.handle(e.msg, e.at)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestRing(t *testing.T) {
+	r := newRing(2)
+	for i := range uint8(3) {
+		if dropped := r.push(inEvent{msg: midi.NoteOn(0, i, 1)}); dropped != (i == 2) { t.Errorf("push %d dropped = %v", i, dropped) }
+	}
+	for _, want := range []uint8{1, 2} {
+		e, ok := r.pop()
+		var ch, key, vel uint8
+		if !ok || !e.msg.GetNoteOn(&ch, &key, &vel) || key != want { t.Errorf("pop = %v, %v, want key %d", e.msg, ok, want) }
+	}
+	if _, ok := r.pop(); ok { t.Error("pop on an empty ring succeeded") }
+}
+
+// producers call the driver callback while a consumer drains, the way a driver and
+// drainInbox share the ring: every message is either taken once, in order, or counted
+// as dropped. Run with -race.
+func TestInboxConcurrent(t *testing.T) {
+	const producers, each = 4, 5000
+	s := &AppState{inbox: newRing(64)}
+	var wg sync.WaitGroup
+	for p := range producers {
+		wg.Go(func() {
+			for i := range each { s.onMessage(midi.NoteOn(uint8(p), uint8(i>>7), uint8(i&0x7f)), 0) }
+		})
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	last, taken := make([]int, producers), 0
+	for i := range last { last[i] = -1 }
+	for finished := false; ; {
+		select {
+		case <-done: finished = true
+		case <-s.inbox.ready:
+		}
+		for e, ok := s.inbox.pop(); ok; e, ok = s.inbox.pop() {
+			var ch, hi, lo uint8
+			if !e.msg.GetNoteOn(&ch, &hi, &lo) { t.Fatalf("popped %v", e.msg) }
+			seq := int(hi)<<7 | int(lo)
+			if seq <= last[ch] { t.Fatalf("producer %d: message %d after %d", ch, seq, last[ch]) }
+			last[ch], taken = seq, taken+1
+		}
+		if finished { break }
+	}
+	s.mu.Lock()
+	dropped := s.inDropped
+	s.mu.Unlock()
+	if taken+dropped != producers*each { t.Errorf("taken %d + dropped %d = %d, want %d", taken, dropped, taken+dropped, producers*each) }
+}
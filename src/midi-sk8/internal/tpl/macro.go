@@ -0,0 +1,52 @@
+package tpl
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var macroRegex = regexp.MustCompile(`@[a-z0-9_]+`)
+
+// ParseMacros reads one "@name = snippet" definition per line, skipping blank lines
+func ParseMacros(text string) (map[string]string, error) {
+	m := map[string]string{}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" { continue }
+		name, body, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !macroRegex.MatchString(name) || macroRegex.FindString(name) != name {
+			return nil, fmt.Errorf("line %d: expected \"@name = snippet\"", i+1)
+		}
+		m[name[1:]] = strings.TrimSpace(body)
+	}
+	for name := range m {
+		if _, err := ExpandMacros("@"+name, m); err != nil { return nil, err }
+	}
+	return m, nil
+}
+
+// ExpandMacros replaces @name references with their snippets, following nested
+// references; unknown names are left alone and cycles are an error
+func ExpandMacros(text string, m map[string]string) (string, error) {
+	return expandFrom(text, m, nil)
+}
+
+func expandFrom(text string, m map[string]string, stack []string) (string, error) {
+	var err error
+	out := macroRegex.ReplaceAllStringFunc(text, func(ref string) string {
+		body, ok := m[ref[1:]]
+		if err != nil || !ok { return ref }
+		path := append(stack[:len(stack):len(stack)], ref[1:])
+		if slices.Contains(stack, ref[1:]) {
+			err = fmt.Errorf("macro @%s refers to itself (@%s)", ref[1:], strings.Join(path, " -> @"))
+			return ref
+		}
+		var x string
+		x, err = expandFrom(body, m, path)
+		return x
+	})
+	return out, err
+}
@@ -0,0 +1,205 @@
+// Package tpl is the template engine: $variable substitution with optional printf
+// specs, @macros, and {} arithmetic with nested parentheses.
+package tpl
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var mathRegex = regexp.MustCompile(`\{([^}]+)\}`)
+var parenRegex = regexp.MustCompile(`\(([^()]+)\)`)
+var varRegex = regexp.MustCompile(`\$([a-z]+)(?::(0?[0-9]*(?:\.[0-9]+)?[dxXobf]))?`)
+
+// SolveBase handles basic arithmetic for a single level of expression
+func SolveBase(expr string) string {
+	expr = strings.ReplaceAll(expr, " ", "")
+	if matches, _ := regexp.MatchString(`[^0-9+\-*/.]`, expr); matches { return "NAN" }
+	operators := "+-*/"
+	var ops []rune
+	var nums []float64
+	curr := ""
+	neg := false
+	// number parses the pending digits, applying any unary signs that came before them
+	number := func() (float64, error) {
+		val, err := strconv.ParseFloat(curr, 64)
+		if neg { val = -val }
+		curr, neg = "", false
+		return val, err
+	}
+	for _, char := range expr {
+		if strings.ContainsRune(operators, char) {
+			// a sign with no digits before it belongs to the next number: -4, 3+-2, --5
+			if curr == "" && (char == '-' || char == '+') { neg = neg != (char == '-'); continue }
+			val, err := number()
+			if err != nil { return "VAL_ERR" }
+			nums = append(nums, val)
+			ops = append(ops, char)
+		} else { curr += string(char) }
+	}
+	lastVal, err := number()
+	if err != nil { return "VAL_ERR" }
+	nums = append(nums, lastVal)
+	if len(nums) == 0 { return "0" }
+	res := nums[0]
+	for i, op := range ops {
+		if i+1 >= len(nums) { break }
+		switch op {
+		case '+': res += nums[i+1]
+		case '-': res -= nums[i+1]
+		case '*': res *= nums[i+1]
+		case '/': 
+			if nums[i+1] != 0 { res /= nums[i+1] } else { return "DIV0" }
+		}
+	}
+	return fmt.Sprintf("%.4f", res)
+}
+
+// Evaluate recursively solves nested parentheses
+func Evaluate(expr string) string {
+	for parenRegex.MatchString(expr) {
+		expr = parenRegex.ReplaceAllStringFunc(expr, func(m string) string {
+			return SolveBase(strings.Trim(m, "()"))
+		})
+	}
+	return SolveBase(expr)
+}
+
+// Vars holds the values substituted for each $name in a template; values are
+// float64 numbers or, for things like timecode, preformatted strings
+type Vars map[string]any
+
+// MsgVars builds the variables every message type provides
+func MsgVars(c, n, v uint8, p uint16) Vars {
+	return Vars{"c": float64(c), "n": float64(n), "v": float64(v), "p": float64(p)}
+}
+
+// FormatVar prints whole numbers without decimals and other numbers like SolveBase
+func FormatVar(v any) string {
+	x, ok := v.(float64)
+	if !ok { return fmt.Sprint(v) }
+	if x == math.Trunc(x) { return strconv.FormatInt(int64(x), 10) }
+	return fmt.Sprintf("%.4f", x)
+}
+
+// FormatSpec applies a printf-style suffix such as $n:03d or $v:02X; numbers are rounded
+// for the integer verbs, and anything without a spec or that is not a number prints as usual
+func FormatSpec(v any, spec string) string {
+	x, ok := v.(float64)
+	if spec == "" || !ok { return FormatVar(v) }
+	if spec[len(spec)-1] == 'f' { return fmt.Sprintf("%"+spec, x) }
+	return fmt.Sprintf("%"+spec, int64(math.Round(x)))
+}
+
+// StripComments drops "#" comment lines from multi-line templates; a single-line
+// template is returned as is, so payloads that start with # keep working
+func StripComments(text string) string {
+	if !strings.Contains(text, "\n") { return text }
+	var keep []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") { keep = append(keep, line) }
+	}
+	return strings.Join(keep, "\n")
+}
+
+// lookup finds the variable a $name refers to: the whole name when vs defines it, else
+// the longest defined prefix, so templates written as n$nv$v or $vel before longer names
+// existed keep expanding $n and $v. It returns the value and the letters left over.
+func lookup(vs Vars, name string) (any, string, bool) {
+	for i := len(name); i > 0; i-- {
+		if x, ok := vs[name[:i]]; ok { return x, name[i:], true }
+	}
+	return nil, "", false
+}
+
+// Failed reports whether Evaluate produced one of its error markers
+func Failed(res string) bool { return res == "NAN" || res == "VAL_ERR" || res == "DIV0" }
+
+// Range bounds the result of every {} expression, to keep a mis-scaled expression
+// inside what the receiver accepts
+type Range struct{ Min, Max float64 }
+
+// ParseRange reads "min,max" such as "0,127" or "0,1"; empty text is no range
+func ParseRange(text string) (*Range, error) {
+	text = strings.TrimSpace(text)
+	if text == "" { return nil, nil }
+	a, b, ok := strings.Cut(text, ",")
+	if !ok { return nil, fmt.Errorf("clamp: expected \"min,max\"") }
+	lo, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	if err != nil { return nil, fmt.Errorf("clamp: %q is not a number", a) }
+	hi, err := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if err != nil { return nil, fmt.Errorf("clamp: %q is not a number", b) }
+	if lo > hi { return nil, fmt.Errorf("clamp: min %g is above max %g", lo, hi) }
+	return &Range{lo, hi}, nil
+}
+
+// clamp limits an evaluated result to r, leaving error markers and in-range results as they are
+func (r *Range) clamp(res string) string {
+	x, err := strconv.ParseFloat(res, 64)
+	if r == nil || err != nil || (x >= r.Min && x <= r.Max) { return res }
+	return fmt.Sprintf("%.4f", math.Min(math.Max(x, r.Min), r.Max))
+}
+
+// Transform strips comment lines, expands @macros, substitutes variables and evaluates
+// {} expressions. The result always carries the error markers in place; the error lists
+// the expressions that failed.
+func Transform(text string, vs Vars, macros map[string]string) (string, error) {
+	return TransformClamped(text, vs, macros, nil)
+}
+
+// TransformClamped is Transform with every {} result limited to r; a nil r clamps nothing.
+// Plain $variables are substituted unclamped.
+func TransformClamped(text string, vs Vars, macros map[string]string, r *Range) (string, error) {
+	return transform(text, vs, macros, r, false)
+}
+
+// TransformWhole is TransformClamped with {} results rounded to whole numbers, for text
+// such as addresses where "9001.0000" is no use
+func TransformWhole(text string, vs Vars, macros map[string]string, r *Range) (string, error) {
+	return transform(text, vs, macros, r, true)
+}
+
+func transform(text string, vs Vars, macros map[string]string, r *Range, whole bool) (string, error) {
+	text, err := ExpandMacros(StripComments(text), macros)
+	if err != nil { return text, err }
+	var bad []string
+	res := varRegex.ReplaceAllStringFunc(text, func(m string) string {
+		sub := varRegex.FindStringSubmatch(m)
+		x, rest, ok := lookup(vs, sub[1])
+		if !ok { return m }
+		// a spec belongs to the whole name, so after a prefix match it is plain text
+		if rest != "" { return FormatVar(x) + m[1+len(sub[1])-len(rest):] }
+		return FormatSpec(x, sub[2])
+	})
+	res = mathRegex.ReplaceAllStringFunc(res, func(match string) string {
+		out := Evaluate(strings.Trim(match, "{}"))
+		if Failed(out) { bad = append(bad, match+" -> "+out); return out }
+		out = r.clamp(out)
+		if x, err := strconv.ParseFloat(out, 64); whole && err == nil { return strconv.FormatInt(int64(math.Round(x)), 10) }
+		return out
+	})
+	if len(bad) > 0 { return res, fmt.Errorf("%s", strings.Join(bad, ", ")) }
+	return res, nil
+}
+
+// UnknownVars lists, once each and in order, the $names in text (after comments and
+// macros) that vs does not define, not even as a prefix; Transform leaves those in the
+// output as typed
+func UnknownVars(text string, vs Vars, macros map[string]string) []string {
+	text, err := ExpandMacros(StripComments(text), macros)
+	if err != nil { return nil }
+	var names []string
+	seen := map[string]bool{}
+	for _, sub := range varRegex.FindAllStringSubmatch(text, -1) {
+		if _, _, ok := lookup(vs, sub[1]); ok || seen[sub[1]] { continue }
+		seen[sub[1]] = true
+		names = append(names, "$"+sub[1])
+	}
+	return names
+}
+
+// HasVars reports whether text contains any $variable
+func HasVars(text string) bool { return varRegex.MatchString(text) }
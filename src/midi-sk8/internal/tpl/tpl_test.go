@@ -0,0 +1,211 @@
+package tpl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSolveBase(t *testing.T) {
+	for _, tc := range []struct{ expr, want string }{
+		{"1+2", "3.0000"},
+		{"3+-2", "1.0000"},
+		{"5*-3", "-15.0000"},
+		{"-4/-2", "2.0000"},
+		{"--5", "5.0000"},
+		{"---5", "-5.0000"},
+		{"+5", "5.0000"},
+		{"3-+2", "1.0000"},
+		{"-+-5", "5.0000"},
+		{"10-2*3", "24.0000"}, // left to right, no precedence
+		{"1 / 4", "0.2500"},
+		{"1/0", "DIV0"},
+		{"2+x", "NAN"},
+		{"1..2", "VAL_ERR"},
+		{"3+", "VAL_ERR"}, // a sign needs a number after it
+		{"3**2", "VAL_ERR"},
+		{"-", "VAL_ERR"},
+	} {
+		if got := SolveBase(tc.expr); got != tc.want { t.Errorf("SolveBase(%q) = %q, want %q", tc.expr, got, tc.want) }
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	for _, tc := range []struct{ expr, want string }{
+		{"(1+2)*3", "9.0000"},
+		{"((2+2)*(1+1))/4", "2.0000"},
+		{"-(3)", "-3.0000"},
+	} {
+		if got := Evaluate(tc.expr); got != tc.want { t.Errorf("Evaluate(%q) = %q, want %q", tc.expr, got, tc.want) }
+	}
+}
+
+// signs ahead of a substituted variable are unary too
+func TestTransformSigns(t *testing.T) {
+	vs := MsgVars(0, 60, 100, 0)
+	for _, tc := range []struct{ text, want string }{
+		{"{--$v}", "100.0000"},
+		{"{-$v}", "-100.0000"},
+		{"{$n+-$v}", "-40.0000"},
+		{"{$p-8192}", "-8192.0000"},
+	} {
+		if got, _ := Transform(tc.text, vs, nil); got != tc.want { t.Errorf("Transform(%q) = %q, want %q", tc.text, got, tc.want) }
+	}
+}
+
+func TestFormatSpec(t *testing.T) {
+	for _, tc := range []struct {
+		v          any
+		spec, want string
+	}{
+		{7.0, "03d", "007"},
+		{255.0, "x", "ff"},
+		{255.0, "02X", "FF"},
+		{5.0, "b", "101"},
+		{8.0, "o", "10"},
+		{0.5, ".2f", "0.50"},
+		{2.6, "d", "3"},
+		{3.0, "", "3"},
+		{0.25, "", "0.2500"},
+		{"01:02:03:04", "03d", "01:02:03:04"},
+	} {
+		if got := FormatSpec(tc.v, tc.spec); got != tc.want { t.Errorf("FormatSpec(%v, %q) = %q, want %q", tc.v, tc.spec, got, tc.want) }
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	for _, tc := range []struct{ text, want string }{
+		{"#single line stays", "#single line stays"},
+		{"# note\nn$n v$v", "n$n v$v"},
+		{"a\n  # indented\nb", "a\nb"},
+		{"a\nb", "a\nb"},
+	} {
+		if got := StripComments(tc.text); got != tc.want { t.Errorf("StripComments(%q) = %q, want %q", tc.text, got, tc.want) }
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		want    *Range
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"0,127", &Range{0, 127}, false},
+		{" -1 , 1 ", &Range{-1, 1}, false},
+		{"5,5", &Range{5, 5}, false},
+		{"127", nil, true},
+		{"a,1", nil, true},
+		{"0,b", nil, true},
+		{"127,0", nil, true},
+	} {
+		got, err := ParseRange(tc.text)
+		if (err != nil) != tc.wantErr { t.Errorf("ParseRange(%q) error = %v, want error %v", tc.text, err, tc.wantErr); continue }
+		if (got == nil) != (tc.want == nil) || got != nil && *got != *tc.want { t.Errorf("ParseRange(%q) = %v, want %v", tc.text, got, tc.want) }
+	}
+}
+
+func TestRangeClamp(t *testing.T) {
+	r := &Range{0, 127}
+	for _, tc := range []struct {
+		r         *Range
+		res, want string
+	}{
+		{r, "64.0000", "64.0000"},
+		{r, "200.0000", "127.0000"},
+		{r, "-3.5000", "0.0000"},
+		{r, "127.0000", "127.0000"},
+		{&Range{-1, 1}, "1.5000", "1.0000"},
+		{r, "DIV0", "DIV0"},
+		{nil, "200.0000", "200.0000"},
+	} {
+		if got := tc.r.clamp(tc.res); got != tc.want { t.Errorf("%v.clamp(%q) = %q, want %q", tc.r, tc.res, got, tc.want) }
+	}
+}
+
+func TestTransformClamped(t *testing.T) {
+	vs := MsgVars(0, 60, 100, 0)
+	r := &Range{0, 127}
+	for _, tc := range []struct {
+		text string
+		r    *Range
+		want string
+	}{
+		{"v{$v*2}", nil, "v200.0000"},
+		{"v{$v*2}", r, "v127.0000"},
+		{"v{$v-200}", r, "v0.0000"},
+		{"v{$v/2}", r, "v50.0000"},
+		{"v$v n{$n}", r, "v100 n60.0000"},
+		{"x{1/0}", r, "xDIV0"},
+	} {
+		got, _ := TransformClamped(tc.text, vs, nil, tc.r)
+		if got != tc.want { t.Errorf("TransformClamped(%q, %v) = %q, want %q", tc.text, tc.r, got, tc.want) }
+	}
+	if _, err := TransformClamped("x{1/0}", vs, nil, r); err == nil || !strings.Contains(err.Error(), "DIV0") { t.Errorf("failed expression error = %v, want DIV0", err) }
+}
+
+func TestTransformWhole(t *testing.T) {
+	vs := MsgVars(1, 60, 100, 0)
+	for _, tc := range []struct{ text, want string }{
+		{"host:{9000+$c}", "host:9001"},
+		{"10.0.0.$c:{9000+($n-58)}", "10.0.0.1:9002"},
+		{"{7/2}", "4"},
+		{"{1/0}", "DIV0"},
+	} {
+		if got, _ := TransformWhole(tc.text, vs, nil, nil); got != tc.want { t.Errorf("TransformWhole(%q) = %q, want %q", tc.text, got, tc.want) }
+	}
+}
+
+func TestTransform(t *testing.T) {
+	vs := MsgVars(2, 64, 127, 8192)
+	macros := map[string]string{"vol": "a{$v/127}", "note": "n$n:03d @vol"}
+	for _, tc := range []struct{ text, want string }{
+		{"c$c n$n v$v p$p", "c2 n64 v127 p8192"},
+		{"@note", "n064 a1.0000"},
+		{"$n:x $n:b", "40 1000000"},
+		{"# comment\n$v", "127"},
+		{"$zz", "$zz"},
+		{"$nope", "64ope"}, // $n, as before longer names
+	} {
+		got, err := Transform(tc.text, vs, macros)
+		if err != nil || got != tc.want { t.Errorf("Transform(%q) = %q, %v, want %q", tc.text, got, err, tc.want) }
+	}
+}
+
+func TestParseMacros(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		wantErr string
+	}{
+		{"@a = x\n\n@b = @a y", ""},
+		{"@a = @a", "refers to itself"},
+		{"@a = @b\n@b = @c\n@c = @a", "refers to itself"},
+		{"a = x", "expected"},
+		{"@a x", "expected"},
+	} {
+		_, err := ParseMacros(tc.text)
+		if tc.wantErr == "" && err != nil || tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("ParseMacros(%q) error = %v, want %q", tc.text, err, tc.wantErr)
+		}
+	}
+	got, err := ExpandMacros("@b", map[string]string{"a": "x", "b": "@a @a @missing"})
+	if err != nil || got != "x x @missing" { t.Errorf("ExpandMacros = %q, %v", got, err) }
+	if _, err := ExpandMacros("@a", map[string]string{"a": "@b", "b": "@a"}); err == nil { t.Error("ExpandMacros did not report the @a -> @b cycle") }
+}
+
+// templates from before $names could be longer than one letter ran the names together
+func TestTransformPrefixVars(t *testing.T) {
+	vs := MsgVars(0, 60, 100, 0)
+	vs["vn"] = 0.5
+	for _, tc := range []struct{ text, want string }{
+		{"n$nv$v", "n60v100"},
+		{"$vel", "100el"},
+		{"$vn", "0.5000"},
+		{"$vnx", "0.5000x"},
+		{"$nv:03d", "60v:03d"},
+		{"$n:03d", "060"},
+		{"$zz", "$zz"},
+	} {
+		if got, _ := Transform(tc.text, vs, nil); got != tc.want { t.Errorf("Transform(%q) = %q, want %q", tc.text, got, tc.want) }
+	}
+	if got := UnknownVars("n$nv$v $zz $vel", vs, nil); len(got) != 1 || got[0] != "$zz" { t.Errorf("UnknownVars = %v, want [$zz]", got) }
+}
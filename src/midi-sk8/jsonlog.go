@@ -0,0 +1,36 @@
+package main
+
+import (
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// logFormats are the values of -log-format; text keeps stdout quiet as before
+var logFormats = []string{"text", "json"}
+
+// eventRecord is one MIDI event as printed by -log-format json
+type eventRecord struct {
+	Time    string `json:"time"`
+	Type    string `json:"type"`
+	Channel *int   `json:"channel,omitempty"`
+	Data1   *int   `json:"data1,omitempty"`
+	Data2   *int   `json:"data2,omitempty"`
+	Bytes   string `json:"bytes"`
+	Out     string `json:"out,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// logEvent writes one JSON line for a handled message when -log-format json is set.
+// It runs on the MIDI callback goroutine, which delivers messages one at a time.
+func (s *AppState) logEvent(msg midi.Message, at time.Time, out string, err error) {
+	if s.jsonLog == nil { return }
+	rec := eventRecord{Time: at.Format(time.RFC3339Nano), Type: msg.Type().String(), Bytes: formatBytes("hex", msg.Bytes()), Out: out}
+	var ch uint8
+	if msg.GetChannel(&ch) { c := int(ch + s.channelBase()); rec.Channel = &c }
+	b := msg.Bytes()
+	if len(b) > 1 { d := int(b[1]); rec.Data1 = &d }
+	if len(b) > 2 { d := int(b[2]); rec.Data2 = &d }
+	if err != nil { rec.Error = err.Error() }
+	s.jsonLog.Encode(rec)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/bits"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// keyWidth is the width of one white key; the full 0-127 range spans 75 of them
+const keyWidth, keyHeight = float32(12), float32(48)
+
+var (
+	whiteKey = color.NRGBA{240, 240, 240, 255}
+	blackKey = color.NRGBA{30, 30, 30, 255}
+)
+
+// isBlack reports whether a MIDI key is a sharp
+func isBlack(key int) bool {
+	switch key % 12 {
+	case 1, 3, 6, 8, 10: return true
+	}
+	return false
+}
+
+// channelColor gives each MIDI channel its own hue, so overlapping parts can be told apart
+func channelColor(ch int) color.NRGBA {
+	h := float64(ch%16) / 16 * 6
+	x := uint8(255 * (1 - math.Abs(math.Mod(h, 2)-1)))
+	switch int(h) {
+	case 0: return color.NRGBA{255, x, 0, 255}
+	case 1: return color.NRGBA{x, 255, 0, 255}
+	case 2: return color.NRGBA{0, 255, x, 255}
+	case 3: return color.NRGBA{0, x, 255, 255}
+	case 4: return color.NRGBA{x, 0, 255, 255}
+	}
+	return color.NRGBA{255, 0, x, 255}
+}
+
+// keyboard is an on-screen keyboard lighting the keys currently held, coloured by the
+// lowest channel holding each one
+type keyboard struct {
+	widget.BaseWidget
+	held [128]uint16 // a bit per channel; only touched on the UI goroutine
+}
+
+func newKeyboard() *keyboard {
+	k := &keyboard{}
+	k.ExtendBaseWidget(k)
+	return k
+}
+
+// note lights or clears a key from any goroutine
+func (k *keyboard) note(ch, key uint8, on bool) {
+	fyne.Do(func() {
+		if on { k.held[key&0x7F] |= 1 << (ch & 0xF) } else { k.held[key&0x7F] &^= 1 << (ch & 0xF) }
+		k.Refresh()
+	})
+}
+
+// clear releases every key from any goroutine
+func (k *keyboard) clear() { fyne.Do(func() { k.held = [128]uint16{}; k.Refresh() }) }
+
+func (k *keyboard) CreateRenderer() fyne.WidgetRenderer {
+	r := &keyboardRenderer{k: k}
+	// white keys go first so the black ones are drawn over them
+	for _, black := range []bool{false, true} {
+		for key := range r.keys {
+			if isBlack(key) != black { continue }
+			r.keys[key] = canvas.NewRectangle(whiteKey)
+			r.keys[key].StrokeColor, r.keys[key].StrokeWidth = blackKey, 1
+			r.objs = append(r.objs, r.keys[key])
+		}
+	}
+	r.Refresh()
+	return r
+}
+
+type keyboardRenderer struct {
+	k    *keyboard
+	keys [128]*canvas.Rectangle
+	objs []fyne.CanvasObject
+}
+
+func (r *keyboardRenderer) Layout(size fyne.Size) {
+	whites := float32(0)
+	for key, rect := range r.keys {
+		if isBlack(key) {
+			w := keyWidth * 0.6
+			rect.Move(fyne.NewPos(whites*keyWidth-w/2, 0))
+			rect.Resize(fyne.NewSize(w, size.Height*0.6))
+			continue
+		}
+		rect.Move(fyne.NewPos(whites*keyWidth, 0))
+		rect.Resize(fyne.NewSize(keyWidth, size.Height))
+		whites++
+	}
+}
+
+func (r *keyboardRenderer) MinSize() fyne.Size { return fyne.NewSize(75*keyWidth, keyHeight) }
+
+func (r *keyboardRenderer) Refresh() {
+	for key, rect := range r.keys {
+		switch {
+		case r.k.held[key] != 0: rect.FillColor = channelColor(bits.TrailingZeros16(r.k.held[key]))
+		case isBlack(key): rect.FillColor = blackKey
+		default: rect.FillColor = whiteKey
+		}
+		rect.Refresh()
+	}
+}
+
+func (r *keyboardRenderer) Objects() []fyne.CanvasObject { return r.objs }
+func (r *keyboardRenderer) Destroy()                     {}
+
+// newKeyboardPane wraps the keyboard in a horizontal scroll starting at middle C, plus
+// the button that shows and hides it
+func (s *AppState) newKeyboardPane() (fyne.CanvasObject, *widget.Button) {
+	scroll := container.NewHScroll(s.piano)
+	scroll.Offset = fyne.NewPos(35*keyWidth, 0)
+	var btn *widget.Button
+	set := func(on bool) {
+		s.showKeys = on
+		if on { scroll.Show(); btn.Importance = widget.HighImportance } else { scroll.Hide(); btn.Importance = widget.LowImportance }
+		btn.Refresh()
+	}
+	btn = widget.NewButtonWithIcon("keys", theme.VisibilityIcon(), func() { set(!s.showKeys) })
+	set(s.showKeys)
+	return scroll, btn
+}
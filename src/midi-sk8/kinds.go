@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// messageKinds are the message groups that can be logged and sent independently
+var messageKinds = []string{"note-on", "note-off", "pitch-bend", "cc", "aftertouch", "mtc", "other"}
+
+// messageKind classifies a message into one of messageKinds
+func messageKind(msg midi.Message) string {
+	switch msg.Type() {
+	case midi.NoteOnMsg: return "note-on"
+	case midi.NoteOffMsg: return "note-off"
+	case midi.PitchBendMsg: return "pitch-bend"
+	case midi.ControlChangeMsg: return "cc"
+	case midi.AfterTouchMsg, midi.PolyAfterTouchMsg: return "aftertouch"
+	case midi.MTCMsg: return "mtc"
+	}
+	return "other"
+}
+
+// newKindGroup builds a horizontal check group with every kind enabled, mirrored into
+// a set the MIDI goroutine can read under s.mu
+func (s *AppState) newKindGroup(set *map[string]bool) *widget.CheckGroup {
+	g := widget.NewCheckGroup(messageKinds, func(selected []string) {
+		m := map[string]bool{}
+		for _, k := range selected { m[k] = true }
+		s.mu.Lock(); *set = m; s.mu.Unlock()
+	})
+	g.Horizontal = true
+	g.SetSelected(messageKinds)
+	return g
+}
+
+// kindOn reports whether kind is enabled in set
+func (s *AppState) kindOn(set *map[string]bool, kind string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return (*set)[kind]
+}
+
+// newTplToggles builds an enable check for every template, mirrored into s.tplOff
+func (s *AppState) newTplToggles() map[string]*widget.Check {
+	m := map[string]*widget.Check{}
+	for key := range s.templates() {
+		c := widget.NewCheck("", func(on bool) { s.mu.Lock(); s.tplOff[key] = !on; s.mu.Unlock() })
+		c.SetChecked(true)
+		m[key] = c
+	}
+	return m
+}
+
+// tplActive reports whether the template behind a render name is enabled; the
+// per-controller "cc N" templates share the cc toggle
+func (s *AppState) tplActive(name string) bool {
+	key, _, _ := strings.Cut(name, " ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.tplOff[key]
+}
+
+// disabledTpls lists the switched-off templates for saving
+func (s *AppState) disabledTpls() []string {
+	var off []string
+	for key, c := range s.tplOn { if !c.Checked { off = append(off, key) } }
+	sort.Strings(off)
+	return off
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// lfoShapes are the waveforms offered for $lfo
+var lfoShapes = []string{"sine", "triangle", "square", "saw"}
+
+// lfoRates are the cycle lengths offered for $lfo, as note divisions of the tempo
+var lfoRates = []string{"4 bars", "2 bars", "1 bar", "1/2", "1/4", "1/8", "1/16"}
+
+// lfoBeats is the length of each rate in beats, with four beats to the bar
+var lfoBeats = map[string]float64{"4 bars": 16, "2 bars": 8, "1 bar": 4, "1/2": 2, "1/4": 1, "1/8": 0.5, "1/16": 0.25}
+
+// lfoSample is the waveform at a phase of 0..1, from -1 to 1. Sine and triangle start
+// at 0 rising, square starts high and saw ramps up from -1.
+func lfoSample(shape string, phase float64) float64 {
+	phase -= math.Floor(phase)
+	switch shape {
+	case "triangle":
+		switch {
+		case phase < 0.25: return 4 * phase
+		case phase < 0.75: return 2 - 4*phase
+		}
+		return 4*phase - 4
+	case "square":
+		if phase < 0.5 { return 1 }
+		return -1
+	case "saw": return 2*phase - 1
+	}
+	return math.Sin(2 * math.Pi * phase)
+}
+
+// lfoValue advances the LFO to now at the current tempo and samples it for $lfo,
+// scaled to 0..1 when unipolar. The phase moves by elapsed time rather than being
+// worked out from the connect time, so a tempo change never makes it jump.
+// Callers hold s.mu.
+func (s *AppState) lfoValue(now time.Time) float64 {
+	beats := lfoBeats[s.lfoRate.Selected]
+	if beats == 0 { beats = 4 }
+	if !s.lfoAt.IsZero() { s.lfoPos += now.Sub(s.lfoAt).Minutes() * s.bpm / beats }
+	s.lfoAt = now
+	s.lfoPos -= math.Floor(s.lfoPos)
+	v := lfoSample(s.lfoShape.Selected, s.lfoPos)
+	if s.lfoUni.Checked { v = (v + 1) / 2 }
+	return v
+}
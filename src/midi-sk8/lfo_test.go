@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLFOSample(t *testing.T) {
+	for _, tc := range []struct {
+		shape       string
+		phase, want float64
+	}{
+		{"sine", 0, 0}, {"sine", 0.25, 1}, {"sine", 0.5, 0}, {"sine", 0.75, -1},
+		{"triangle", 0, 0}, {"triangle", 0.125, 0.5}, {"triangle", 0.25, 1}, {"triangle", 0.5, 0}, {"triangle", 0.75, -1}, {"triangle", 0.875, -0.5},
+		{"square", 0, 1}, {"square", 0.49, 1}, {"square", 0.5, -1}, {"square", 0.99, -1},
+		{"saw", 0, -1}, {"saw", 0.5, 0}, {"saw", 0.75, 0.5},
+		{"saw", 1.25, -0.5}, {"triangle", -0.75, 1}, // phases wrap
+		{"unknown", 0.25, 1}, // sine
+	} {
+		if got := lfoSample(tc.shape, tc.phase); math.Abs(got-tc.want) > 1e-9 { t.Errorf("lfoSample(%q, %v) = %v, want %v", tc.shape, tc.phase, got, tc.want) }
+	}
+}
+
+// at 120 bpm a 1/4 cycle lasts half a second
+func TestLFOValue(t *testing.T) {
+	s := newAppState()
+	s.bpm = 120
+	s.lfoRate.SetSelected("1/4")
+	s.lfoShape.SetSelected("saw")
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		at   time.Duration
+		uni  bool
+		want float64
+	}{
+		{0, false, -1},
+		{125 * time.Millisecond, false, -0.5},
+		{250 * time.Millisecond, false, 0},
+		{500 * time.Millisecond, false, -1}, // a full cycle
+		{625 * time.Millisecond, true, 0.25},
+	} {
+		s.lfoUni.SetChecked(tc.uni)
+		if got := s.lfoValue(t0.Add(tc.at)); math.Abs(got-tc.want) > 1e-9 { t.Errorf("at %v: lfo %v, want %v", tc.at, got, tc.want) }
+	}
+	// a tempo change carries on from the current phase
+	s.lfoUni.SetChecked(false)
+	s.bpm = 60
+	if got := s.lfoValue(t0.Add(875 * time.Millisecond)); math.Abs(got) > 1e-9 { t.Errorf("after halving the tempo: lfo %v, want 0", got) }
+}
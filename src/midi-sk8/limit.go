@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// rateModes are what happens to a payload when the rate limit is reached
+var rateModes = []string{"drop", "queue"}
+
+// maxQueued bounds the payloads waiting for the limiter; beyond it they are dropped
+const maxQueued = 256
+
+// errRateLimited is returned by send when the rate limit drops a payload
+var errRateLimited = errors.New("rate limited")
+
+// bucket is a token bucket: it holds up to burst tokens, refills at rate per second
+// and each payload takes one. Callers hold s.mu.
+type bucket struct {
+	rate, burst, tokens float64
+	last                time.Time
+}
+
+// newBucket starts full so a burst can go out straight away
+func newBucket(rate, burst int, now time.Time) *bucket {
+	b := float64(max(burst, 1))
+	return &bucket{rate: float64(rate), burst: b, tokens: b, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// allow takes a token if one is there
+func (b *bucket) allow(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 { return false }
+	b.tokens--
+	return true
+}
+
+// reserve takes a token, going into debt if need be, and returns how long to wait
+// before using it
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 { return 0 }
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// queued is a payload framed for every sink, waiting for a token or for its due time
+type queued struct {
+	out  []framed
+	dest string
+}
+
+// newLimiter reads the rate limit settings, returning nil when the limit is off
+func (s *AppState) newLimiter() (*bucket, error) {
+	rate, err := parseNonNegative("rate limit", s.rateLimit.Text)
+	if err != nil || rate == 0 { return nil, err }
+	burst, err := parseNonNegative("rate burst", s.rateBurst.Text)
+	if err != nil { return nil, err }
+	if burst == 0 { burst = rate }
+	return newBucket(rate, burst, time.Now()), nil
+}
+
+// limit applies the rate limit to one payload, framed for every sink, so a dropped one
+// goes to none of them. It returns true when the caller should write it now; queued
+// payloads are written later by drainQueue.
+func (s *AppState) limit(q queued) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bucket == nil { return true, nil }
+	if s.queue != nil {
+		select {
+		case s.queue <- q: return false, nil
+		default: s.dropped++; return false, errRateLimited
+		}
+	}
+	if s.bucket.allow(time.Now()) { return true, nil }
+	s.dropped++
+	return false, errRateLimited
+}
+
+// drainQueue writes queued payloads as tokens become available until the session ends
+func (s *AppState) drainQueue(done <-chan struct{}, q <-chan queued) {
+	for {
+		var p queued
+		select {
+		case <-done: return
+		case p = <-q:
+		}
+		s.mu.Lock()
+		wait := s.bucket.reserve(time.Now())
+		s.mu.Unlock()
+		select {
+		case <-done: return
+		case <-time.After(wait):
+		}
+		s.deliver(p)
+	}
+}
+
+// droppedCounts reports how many payloads the rate limit dropped and how many
+// messages overflowed the input buffer
+func (s *AppState) droppedCounts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped, s.inDropped
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestBucketAllow(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBucket(10, 3, t0)
+	for _, tc := range []struct {
+		at   time.Duration
+		want bool
+	}{
+		{0, true}, {0, true}, {0, true}, // the burst
+		{0, false},
+		{50 * time.Millisecond, false}, // half a token
+		{100 * time.Millisecond, true},
+		{100 * time.Millisecond, false},
+		{10 * time.Second, true}, // a long gap refills only to burst
+		{10 * time.Second, true},
+		{10 * time.Second, true},
+		{10 * time.Second, false},
+	} {
+		if got := b.allow(t0.Add(tc.at)); got != tc.want { t.Errorf("allow at %v = %v, want %v (tokens %.2f)", tc.at, got, tc.want, b.tokens) }
+	}
+	if b := newBucket(5, 0, t0); !b.allow(t0) || b.allow(t0) { t.Error("burst 0 does not let exactly one payload through") }
+}
+
+func TestBucketReserve(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBucket(10, 2, t0)
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond} {
+		if got := b.reserve(t0).Round(time.Millisecond); got != want { t.Errorf("reserve %d = %v, want %v", i, got, want) }
+	}
+	if got := b.reserve(t0.Add(time.Second)).Round(time.Millisecond); got != 0 { t.Errorf("reserve after the debt is paid = %v, want 0", got) }
+}
+
+func TestNewLimiter(t *testing.T) {
+	for _, tc := range []struct {
+		rate, burst string
+		want        float64 // burst, 0 for no limiter
+		wantErr     bool
+	}{
+		{"0", "5", 0, false},
+		{"", "", 0, false},
+		{"100", "", 100, false},
+		{"100", "0", 100, false},
+		{"100", "8", 8, false},
+		{"-1", "", 0, true},
+		{"100", "x", 0, true},
+	} {
+		s := newAppState()
+		s.rateLimit.SetText(tc.rate)
+		s.rateBurst.SetText(tc.burst)
+		b, err := s.newLimiter()
+		if (err != nil) != tc.wantErr { t.Errorf("rate %q burst %q: error = %v", tc.rate, tc.burst, err); continue }
+		got := 0.0
+		if b != nil { got = b.burst }
+		if got != tc.want { t.Errorf("rate %q burst %q: burst = %v, want %v", tc.rate, tc.burst, got, tc.want) }
+	}
+}
+
+// with the drop mode, sends past the burst fail and are counted
+func TestLimitDrops(t *testing.T) {
+	s := newAppState()
+	r := testOutput(s)
+	s.bucket = newBucket(1, 2, time.Now())
+	for i, want := range []error{nil, nil, errRateLimited, errRateLimited} {
+		if err := s.send("x"); err != want { t.Errorf("send %d: error = %v, want %v", i, err, want) }
+	}
+	if got := r.got(); len(got) != 2 { t.Errorf("sent %q, want two payloads", got) }
+	if dropped, _ := s.droppedCounts(); dropped != 2 { t.Errorf("dropped = %d, want 2", dropped) }
+}
+
+// in queue mode, notes still sounding when the session ends are released at once
+// rather than into a queue nothing drains any more, even with the bucket empty
+func TestDisconnectReleasesQueued(t *testing.T) {
+	s := newAppState()
+	r := testOutput(s)
+	s.noteOnTpl.SetText("")
+	s.noteOffTpl.SetText("off n$n")
+	ctx, end := context.WithCancel(context.Background())
+	s.sessionCtx, s.endSession = ctx, end
+	q := make(chan queued, maxQueued)
+	s.bucket, s.queue = newBucket(1, 1, time.Now()), q
+	s.bucket.tokens = 0
+	s.workers.Go(func() { s.drainQueue(ctx.Done(), q) })
+	s.dispatch(midi.NoteOn(0, 60, 100), time.Now())
+	s.dispatch(midi.NoteOn(0, 61, 100), time.Now())
+	s.disconnect()
+	if got := r.got(); !slices.Equal(got, []string{"off n60", "off n61"}) { t.Errorf("sent %q, want [off n60 off n61]", got) }
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// the lines kept per log, whatever the session length
+const defaultLogLines, minLogLines, maxLogLines = 1000, 1000, 10000
+
+// logRing holds the newest lines of one log in storage allocated once, so a long
+// session neither grows the log nor copies it to trim. Only used on the UI goroutine.
+type logRing struct {
+	lines    []string
+	start, n int
+	pushed   int // lines ever pushed, numbering them
+	// what the entry showed when last drawn: lines from up to to, the bytes of them
+	// dropped since, and the newest one as drawn if setLast has replaced it
+	from, to, cut int
+	edited        *string
+}
+
+func newLogRing(size int) *logRing { return &logRing{lines: make([]string, size)} }
+
+// push appends a line, overwriting the oldest once full, and returns the line dropped
+func (r *logRing) push(line string) (string, bool) {
+	if r.n < len(r.lines) { r.lines[(r.start+r.n)%len(r.lines)] = line; r.n++; r.pushed++; return "", false }
+	old := r.lines[r.start]
+	if g := r.pushed - r.n; g >= r.from && g < r.to { r.cut += len(old) + 1 }
+	r.lines[r.start] = line
+	r.start = (r.start + 1) % len(r.lines)
+	r.pushed++
+	return old, true
+}
+
+// last returns the newest line, or "" when empty
+func (r *logRing) last() string {
+	if r.n == 0 { return "" }
+	return r.lines[(r.start+r.n-1)%len(r.lines)]
+}
+
+// setLast replaces the newest line
+func (r *logRing) setLast(line string) {
+	if r.n == 0 { return }
+	i := (r.start + r.n - 1) % len(r.lines)
+	if r.edited == nil && r.pushed == r.to { old := r.lines[i]; r.edited = &old }
+	r.lines[i] = line
+}
+
+func (r *logRing) reset() { clear(r.lines); r.start, r.n, r.from, r.to, r.cut, r.edited = 0, 0, 0, 0, 0, nil }
+
+// resized copies the newest lines into a ring of a new size
+func (r *logRing) resized(size int) *logRing {
+	nr := newLogRing(size)
+	for i := max(r.n-size, 0); i < r.n; i++ { nr.lines[nr.n] = r.lines[(r.start+i)%len(r.lines)]; nr.n++ }
+	nr.pushed = r.pushed
+	return nr
+}
+
+// text renders the lines for the entry, one per row with a trailing newline
+func (r *logRing) text() string {
+	var b strings.Builder
+	for i := range r.n {
+		b.WriteString(r.lines[(r.start+i)%len(r.lines)])
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// render turns drawn, the entry's text when render last ran, into the text for the
+// lines held now: what fell off the top is cut and only the lines since are added, so a
+// repaint costs one copy of the text rather than a walk over the whole ring. With
+// nothing to reuse it falls back to text.
+func (r *logRing) render(drawn string) string {
+	first := r.pushed - r.n
+	keep, next := len(drawn)-r.cut, r.to
+	if r.edited != nil { keep, next = keep-len(*r.edited)-1, next-1 }
+	if r.to <= first || next < first || r.cut > len(drawn) || keep < 0 || r.to > r.pushed { return r.mark(r.text()) }
+	var b strings.Builder
+	b.Grow(keep + r.bytes(next))
+	b.WriteString(drawn[r.cut : r.cut+keep])
+	for g := next; g < r.pushed; g++ {
+		b.WriteString(r.lines[(r.start+g-first)%len(r.lines)])
+		b.WriteByte('\n')
+	}
+	return r.mark(b.String())
+}
+
+// bytes is the length of the text of the lines from g on
+func (r *logRing) bytes(g int) int {
+	n, first := 0, r.pushed-r.n
+	for ; g < r.pushed; g++ { n += len(r.lines[(r.start+g-first)%len(r.lines)]) + 1 }
+	return n
+}
+
+// mark records text as drawn for the next render
+func (r *logRing) mark(text string) string {
+	r.from, r.to, r.cut, r.edited = r.pushed-r.n, r.pushed, 0, nil
+	return text
+}
+
+// parseLogLines reads the log size setting; empty means the default
+func parseLogLines(text string) (int, error) {
+	if text == "" { return defaultLogLines, nil }
+	n, err := strconv.Atoi(text)
+	if err != nil || n < minLogLines || n > maxLogLines { return 0, fmt.Errorf("log lines must be %d-%d", minLogLines, maxLogLines) }
+	return n, nil
+}
+
+// logRingFor returns the ring behind a log entry, sized to the current setting
+func (s *AppState) logRingFor(e *widget.Entry) *logRing {
+	size, err := parseLogLines(s.logLines.Text)
+	if err != nil { size = defaultLogLines }
+	r := s.logs[e]
+	switch {
+	case r == nil: r = newLogRing(size)
+	case len(r.lines) != size: r = r.resized(size)
+	default: return r
+	}
+	s.logs[e] = r
+	return r
+}
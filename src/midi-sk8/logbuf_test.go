@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestLogRing(t *testing.T) {
+	r := newLogRing(3)
+	for i := range 3 { if _, dropped := r.push(fmt.Sprint(i)); dropped { t.Fatalf("push %d dropped a line before the ring was full", i) } }
+	if old, dropped := r.push("3"); !dropped || old != "0" { t.Errorf("push = %q, %v, want \"0\", true", old, dropped) }
+	r.setLast("3 (x2)")
+	if got := r.last(); got != "3 (x2)" { t.Errorf("last = %q", got) }
+	if got := r.text(); got != "1\n2\n3 (x2)\n" { t.Errorf("text = %q", got) }
+	if got := r.resized(2).text(); got != "2\n3 (x2)\n" { t.Errorf("resized(2) = %q", got) }
+	if got := r.resized(5).text(); got != "1\n2\n3 (x2)\n" { t.Errorf("resized(5) = %q", got) }
+	r.reset()
+	if got := r.text() + r.last(); got != "" { t.Errorf("after reset = %q", got) }
+}
+
+// render must always give what text would, however the pushes, collapses and drops
+// between repaints fall
+func TestLogRingRender(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	r, drawn := newLogRing(8), ""
+	for i := range 5000 {
+		switch k := rnd.IntN(20); {
+		case k == 0: r.reset(); drawn = ""
+		case k == 1: r = r.resized(4 + rnd.IntN(8))
+		case k < 6: r.setLast(fmt.Sprintf("%s (x%d)", r.last(), i))
+		case k < 8: r.push(fmt.Sprintf("line %d\nsecond row", i))
+		default: r.push(fmt.Sprintf("line %d", i))
+		}
+		if rnd.IntN(3) > 0 { continue }
+		want := r.text()
+		if drawn = r.render(drawn); drawn != want { t.Fatalf("step %d: render = %q, want %q", i, drawn, want) }
+	}
+}
+
+// sustained input into a full log: each line costs one copy of the text whatever the
+// ring size, against a walk over every line for text
+func BenchmarkLogRingRender(b *testing.B) {
+	for _, size := range []int{minLogLines, maxLogLines} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			r := newLogRing(size)
+			for i := range size { r.push(fmt.Sprintf("in  ch1 note on  %3d %3d", i%128, 100)) }
+			drawn := r.render("")
+			b.ReportAllocs()
+			for b.Loop() { r.push("in  ch1 note on   60 100"); drawn = r.render(drawn) }
+		})
+	}
+}
+
+func BenchmarkLogRingText(b *testing.B) {
+	for _, size := range []int{minLogLines, maxLogLines} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			r := newLogRing(size)
+			for i := range size { r.push(fmt.Sprintf("in  ch1 note on  %3d %3d", i%128, 100)) }
+			b.ReportAllocs()
+			for b.Loop() { r.push("in  ch1 note on   60 100"); _ = r.text() }
+		})
+	}
+}
+
+// a burst of lines between repaints is drawn once
+func BenchmarkLogRingBurst(b *testing.B) {
+	r := newLogRing(minLogLines)
+	for range minLogLines { r.push("in  ch1 note on   60 100") }
+	drawn := r.render("")
+	b.ReportAllocs()
+	for b.Loop() {
+		for range 100 { r.push("in  ch1 note on   60 100") }
+		drawn = r.render(drawn)
+	}
+}
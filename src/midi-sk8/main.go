@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	_ "embed"
+	"flag"
 	"fmt"
 	"image/color"
 	"net"
-	"regexp"
-	"strconv"
+	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -16,17 +20,15 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"gitlab.com/gomidi/midi/v2"
 	"gitlab.com/gomidi/midi/v2/drivers"
 	_ "gitlab.com/gomidi/midi/v2/drivers/rtmididrv"
+
+	"midi-sk8/internal/tpl"
 )
 
 //go:embed icon.png
 var iconBytes []byte
 
-var mathRegex = regexp.MustCompile(`\{([^}]+)\}`)
-var parenRegex = regexp.MustCompile(`\(([^()]+)\)`)
-
 // customTheme improves visibility and scaling
 type customTheme struct{ fyne.Theme }
 func (m customTheme) Size(name fyne.ThemeSizeName) float32 {
@@ -36,242 +38,777 @@ func (m customTheme) Size(name fyne.ThemeSizeName) float32 {
 }
 
 type AppState struct {
+	mu          sync.Mutex
+	prefs       fyne.Preferences
 	udpConn     net.Conn
+	udpOut      *batcher
+	maxUDP      *widget.Entry
+	onOversize  *widget.Select
+	tcpConns    map[string]net.Conn
+	rtt         *roundTrip
+	echoing     bool
+	seqTags     *widget.Check
+	seq         int
+	sinks       []*sink
+	sinkRows    []*sinkRow
+	bucket      *bucket
+	queue       chan queued
+	dropped     int
+	sentBytes   int
+	sentWrites  int
+	rateLimit   *widget.Entry
+	rateBurst   *widget.Entry
+	rateMode    *widget.Select
+	delayMs     *widget.Entry
+	jitterMs    *widget.Entry
+	delay       time.Duration
+	jitter      time.Duration
+	delays      chan delayed
+	lastDue     time.Time
+	router      *router
+	routeTpl    string
+	ports       map[string]string
+	typePorts   *widget.Entry
 	stopMidi    func()
+	inbox       *ring
+	inBuf       *widget.Entry
+	inDropped   int
+	sessionCtx  context.Context
+	endSession  context.CancelFunc
+	workers     sync.WaitGroup
+	connecting  bool
+	connectBtn  *widget.Button
+	connectedAt time.Time
+	clock       func() time.Time
+	renderErr   func(name string, err error)
+	firstMsgAt  time.Time
+	stampMode   *widget.Select
+	byteMode    *widget.Select
 	isPaused    bool
+	trigger     *trigger
+	trigEntry   *widget.Entry
+	autoPause   *widget.Check
+	armed       bool
+	follow      bool
+	minimal     bool
+	collapse    *widget.Check
+	clearOnConn *widget.Check
+	onTop       *widget.Check
+	repeats     map[*widget.Entry]repeat
+	logShift    map[*widget.Entry]int
+	dirty       map[*widget.Entry]bool
+	repaintMs   *widget.Entry
+	repaint     time.Duration
+	paintDue    bool
+	flashes     map[*canvas.Circle]flashState
+	logs        map[*widget.Entry]*logRing
+	logLines    *widget.Entry
 	isDark      bool
+	themeMode   *widget.Select
 	midiLog     *widget.Entry
+	jsonLog     *json.Encoder
+	monitor     *widget.Table
+	rows        []monitorRow
+	capture     *capture
+	stats       map[statKey]ctlStat
 	udpLog      *widget.Entry
+	echoLog     *widget.Entry
+	echoPort    *widget.Entry
+	echoConn    net.PacketConn
+	echoBtn     *widget.Button
 	addrEntry   *widget.Entry
 	portEntry   *widget.Entry
+	outMode     *widget.Select
+	sockPath    *widget.Entry
+	execCmd     *widget.Entry
+	execEach    *widget.Check
+	serialPort  *widget.SelectEntry
+	serialBaud  *widget.Entry
+	batchSize   *widget.Entry
+	batchMs     *widget.Entry
+	framing     *widget.Select
+	encoding    *widget.Select
+	onError     *widget.Select
+	strictVars  *widget.Check
+	deadband    *widget.Entry
+	logGroup    *widget.CheckGroup
+	sendGroup   *widget.CheckGroup
+	logKinds    map[string]bool
+	sendKinds   map[string]bool
+	chanNumbers *widget.Select
+	solo        int
+	soloSel     *widget.Select
+	bpm         float64
+	taps        []time.Time
+	lastSent    map[ctlKey]int
+	glideMs     *widget.Entry
+	glideHz     *widget.Entry
+	glideDur    time.Duration
+	glides      map[ctlKey]*glide
 	midiSelect  *widget.Select
+	wantIn      string
+	midiOutSel  *widget.Select
+	midiOut     drivers.Out
+	driverSel   *widget.Select
+	virtualIn   *widget.Check
+	virtualName *widget.Entry
+	retryCount  *widget.Entry
+	retryMs     *widget.Entry
 	noteOnTpl   *widget.Entry
 	noteOffTpl  *widget.Entry
+	mergeOff    *widget.Check
+	latch       *widget.Check
+	latched     map[noteKey]bool
 	pbTpl       *widget.Entry
+	bendRange   *widget.Entry
+	ccTpl       *widget.Entry
+	modeTpl     *widget.Entry
+	atTpl       *widget.Entry
+	polyAtTpl   *widget.Entry
+	velCurve    *widget.Select
+	relCurve    *widget.Select
+	curveAt     *widget.Check
+	ccMapEntry  *widget.Entry
+	ccTpls      map[uint8]string
+	macroEntry  *widget.Entry
+	watchPath   *widget.Entry
+	presetURI   fyne.URI
+	stopWatch   func()
+	tplOn       map[string]*widget.Check
+	tplOff      map[string]bool
+	macros      map[string]string
+	clamp       *tpl.Range
+	lfoRate     *widget.Select
+	lfoShape    *widget.Select
+	lfoUni      *widget.Check
+	lfoPos      float64
+	lfoAt       time.Time
+	clampEntry  *widget.Entry
+	invertEntry *widget.Entry
+	adjEntry    *widget.Entry
+	chanAdj     map[uint8]chanAdjust
+	shifted     map[noteKey]uint8
+	invert      map[uint8]bool
+	mpeCheck    *widget.Check
+	mpeMaster   *widget.Select
+	mpeMembers  *widget.Entry
+	mpeTpl      *widget.Entry
+	voices      [16]voice
+	held        map[noteKey]heldNote
+	lastNote    map[uint8]uint8
+	bendNote    *widget.Check
+	piano       *keyboard
+	showKeys    bool
+	mtcTpl      *widget.Entry
+	mtcMode     *widget.Select
+	mtc         mtcState
 	manualEntry *widget.Entry
+	scriptMode  *widget.Check
+	scriptEntry *widget.Entry
+	scriptMs    *widget.Entry
+	stopScript  func()
+	lastPayload string
 	indicator   *canvas.Circle
+	outDot      *canvas.Circle
+	colorIn     *widget.Entry
+	colorOut    *widget.Entry
+	connDot     *canvas.Circle
+	conn        connState
+	dialMs      *widget.Entry
+	statusLabel *widget.Label
 }
 
-// solveBase handles basic arithmetic for a single level of expression
-func solveBase(expr string) string {
-	expr = strings.ReplaceAll(expr, " ", "")
-	if matches, _ := regexp.MatchString(`[^0-9+\-*/.]`, expr); matches { return "NAN" }
-	operators := "+-*/"
-	var ops []rune
-	var nums []float64
-	curr := ""
-	for _, char := range expr {
-		if strings.ContainsRune(operators, char) {
-			if curr == "" && char == '-' { curr = "-"; continue }
-			val, err := strconv.ParseFloat(curr, 64)
-			if err != nil { return "VAL_ERR" }
-			nums = append(nums, val)
-			ops = append(ops, char)
-			curr = ""
-		} else { curr += string(char) }
-	}
-	lastVal, err := strconv.ParseFloat(curr, 64)
-	if err != nil { return "VAL_ERR" }
-	nums = append(nums, lastVal)
-	if len(nums) == 0 { return "0" }
-	res := nums[0]
-	for i, op := range ops {
-		if i+1 >= len(nums) { break }
-		switch op {
-		case '+': res += nums[i+1]
-		case '-': res -= nums[i+1]
-		case '*': res *= nums[i+1]
-		case '/': 
-			if nums[i+1] != 0 { res /= nums[i+1] } else { return "DIV0" }
+// vars holds the values substituted for each $name in a template
+type vars = tpl.Vars
+
+// msgVars builds the variables every message type provides
+func msgVars(c, n, v uint8, p uint16) vars { return tpl.MsgVars(c, n, v, p) }
+
+// transform runs a template through the engine with the current macros and clamp
+func (s *AppState) transform(text string, vs vars) (string, error) {
+	s.mu.Lock()
+	r := s.clamp
+	s.mu.Unlock()
+	return tpl.TransformClamped(text, vs, s.macroMap(), r)
+}
+
+// macroMap returns the parsed macros for the hot path
+func (s *AppState) macroMap() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.macros
+}
+
+// channelBase is what raw channel 0 is shown and substituted as
+func (s *AppState) channelBase() uint8 {
+	if s.chanNumbers.Selected == "0-15" { return 0 }
+	return 1
+}
+
+// renumber rewrites the settings that name channels in the chosen numbering, so they
+// still mean the same channels; it runs on the UI goroutine
+func (s *AppState) renumber() {
+	base := s.channelBase()
+	s.mu.Lock(); adj, solo := s.chanAdj, s.solo; s.mu.Unlock()
+	if len(adj) > 0 { s.adjEntry.SetText(formatChanAdjust(adj, base)) }
+	if s.soloSel == nil { return }
+	s.soloSel.Options = soloOptions(base)
+	s.soloSel.SetSelected(s.soloSel.Options[solo+1])
+}
+
+// render transforms a named template, applying the on-error setting when an expression fails.
+// Switched-off templates, results that are only whitespace and, in strict mode, templates
+// using variables this message does not define render to nothing.
+// Channels stay raw up to here; only the substituted $c is shifted to the chosen numbering.
+func (s *AppState) render(name, text string, vs vars) string {
+	if !s.tplActive(name) { return "" }
+	if c, ok := vs["c"].(float64); ok { vs["c"] = c + float64(s.channelBase()) }
+	now := s.clock()
+	s.mu.Lock(); elapsed, bpm, lfo := s.elapsed(now), s.bpm, s.lfoValue(now); s.mu.Unlock()
+	vs["t"], vs["tms"], vs["bpm"], vs["lfo"] = elapsed.Seconds(), float64(elapsed.Milliseconds()), bpm, lfo
+	if s.strictVars.Checked {
+		if unknown := tpl.UnknownVars(text, vs, s.macroMap()); len(unknown) > 0 {
+			s.appendLog(s.udpLog, fmt.Sprintf("! %s template not sent: unknown variable %s", name, strings.Join(unknown, ", ")))
+			return ""
 		}
 	}
-	return fmt.Sprintf("%.4f", res)
+	out, err := s.transform(text, vs)
+	if err != nil && s.renderErr != nil { s.renderErr(name, err) }
+	if strings.TrimSpace(out) == "" { return "" }
+	if err == nil { return out }
+	if s.onError.Selected == "suppress send" {
+		s.appendLog(s.udpLog, fmt.Sprintf("! %s template not sent: %v", name, err))
+		return ""
+	}
+	return out
 }
 
-// evaluate recursively solves nested parentheses
-func evaluate(expr string) string {
-	for parenRegex.MatchString(expr) {
-		expr = parenRegex.ReplaceAllStringFunc(expr, func(m string) string {
-			return solveBase(strings.Trim(m, "()"))
-		})
+// elapsed is the time connected at now, 0 while not connected; the caller holds s.mu
+func (s *AppState) elapsed(now time.Time) time.Duration {
+	if s.connectedAt.IsZero() { return 0 }
+	return now.Sub(s.connectedAt)
+}
+
+// clearLogs empties every log and the decoded monitor; it must run on the UI goroutine
+func (s *AppState) clearLogs() {
+	for _, e := range []*widget.Entry{s.midiLog, s.udpLog, s.echoLog} {
+		if r := s.logs[e]; r != nil { r.reset() }
+		delete(s.repeats, e)
+		delete(s.logShift, e)
+		e.SetText("")
 	}
-	return solveBase(expr)
+	s.clearMonitor()
 }
 
-func (s *AppState) transform(tpl string, c, n, v uint8, p uint16) string {
-	res := tpl
-	res = strings.ReplaceAll(res, "$c", strconv.Itoa(int(c)))
-	res = strings.ReplaceAll(res, "$n", strconv.Itoa(int(n)))
-	res = strings.ReplaceAll(res, "$v", strconv.Itoa(int(v)))
-	res = strings.ReplaceAll(res, "$p", strconv.Itoa(int(p)))
-	return mathRegex.ReplaceAllStringFunc(res, func(match string) string {
-		return evaluate(strings.Trim(match, "{}"))
-	})
+// repeat is the last line appended to a log and how many times in a row it came
+type repeat struct {
+	line, shown string
+	n           int
 }
 
-func (s *AppState) flash() {
+// appendLog adds a line to a log entry from any goroutine. The entry shows a ring of
+// the newest log-lines lines, so the oldest falls off once it is full. While following,
+// the cursor sits on the empty row after the newest line so the entry scrolls to it;
+// otherwise the cursor keeps pointing at the same text. With collapsing on, a line
+// equal to the previous one bumps an (xN) count on it instead.
+func (s *AppState) appendLog(e *widget.Entry, line string) {
 	fyne.Do(func() {
-		s.indicator.FillColor = color.NRGBA{R: 0, G: 255, B: 0, A: 255}
-		s.indicator.Refresh()
+		ring := s.logRingFor(e)
+		if r := s.repeats[e]; s.collapse.Checked && r.n > 0 && r.line == line && ring.last() == r.shown {
+			r.n++
+			r.shown = fmt.Sprintf("%s (x%d)", line, r.n)
+			ring.setLast(r.shown)
+			s.repeats[e] = r
+		} else {
+			s.repeats[e] = repeat{line, line, 1}
+			if old, dropped := ring.push(line); dropped { s.logShift[e] += strings.Count(old, "\n") + 1 }
+		}
+		s.paintLog(e)
 	})
+}
+
+// connState is what the connection dot shows
+type connState int
+
+const (
+	connIdle connState = iota
+	connUp
+	connDown
+)
+
+var connColors = map[connState]color.NRGBA{
+	connIdle: {R: 80, G: 80, B: 80, A: 255}, connUp: {R: 0, G: 200, B: 0, A: 255}, connDown: {R: 220, G: 0, B: 0, A: 255},
+}
+
+// setConnState recolors the connection dot from any goroutine, only when the state changes
+func (s *AppState) setConnState(st connState) {
+	s.mu.Lock()
+	changed := s.conn != st
+	s.conn = st
+	s.mu.Unlock()
+	if !changed { return }
+	fyne.Do(func() { s.connDot.FillColor = connColors[st]; s.connDot.Refresh() })
+}
+
+// parseColor reads a #rrggbb color
+func parseColor(text string) (color.NRGBA, error) {
+	var c color.NRGBA
+	if _, err := fmt.Sscanf(strings.TrimSpace(text), "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return c, fmt.Errorf("%q is not a #rrggbb color", text)
+	}
+	c.A = 255
+	return c, nil
+}
+
+// colorOr is the color in e, or def when e is blank or invalid
+func colorOr(e *widget.Entry, def color.NRGBA) color.NRGBA {
+	if c, err := parseColor(e.Text); err == nil { return c }
+	return def
+}
+
+// flash lights an indicator in c for a moment, at the next repaint when they are coalesced
+func (s *AppState) flash(dot *canvas.Circle, c color.NRGBA) {
+	if s.flashLater(dot, c) { return }
+	fyne.Do(func() { dot.FillColor = c; dot.Refresh() })
 	go func() {
-		time.Sleep(time.Millisecond * 100)
-		fyne.Do(func() {
-			s.indicator.FillColor = color.NRGBA{R: 80, G: 80, B: 80, A: 255}
-			s.indicator.Refresh()
-		})
+		time.Sleep(flashFor)
+		fyne.Do(func() { dot.FillColor = dotOff; dot.Refresh() })
 	}()
 }
 
+// flashIn shows MIDI arriving
+func (s *AppState) flashIn() { s.flash(s.indicator, colorOr(s.colorIn, color.NRGBA{R: 0, G: 255, B: 0, A: 255})) }
+
+// flashOut shows a payload leaving, in amber when it was held back because output is disarmed
+func (s *AppState) flashOut() {
+	if !s.armed { s.flash(s.outDot, color.NRGBA{R: 255, G: 180, B: 0, A: 255}); return }
+	s.flash(s.outDot, colorOr(s.colorOut, color.NRGBA{R: 0, G: 160, B: 255, A: 255}))
+}
+
 // This variable is populated by the -X ldflag in goreleaser
 var version = "dev"
 
-func main() {
-	a := app.NewWithID("com.sk8r.midi-udp")
-	a.Settings().SetTheme(customTheme{theme.LightTheme()})
-	w := a.NewWindow("midi-sk8 " + version)
-	w.SetIcon(fyne.NewStaticResource("icon.png", iconBytes))
-
+// newAppState builds the settings and widgets at their defaults, before saved
+// preferences are loaded; it needs a Fyne app but no window
+func newAppState() *AppState {
 	s := &AppState{
 		midiLog: widget.NewMultiLineEntry(), udpLog: widget.NewMultiLineEntry(),
+		echoLog: widget.NewMultiLineEntry(), echoPort: widget.NewEntry(),
 		addrEntry: widget.NewEntry(), portEntry: widget.NewEntry(),
-		midiSelect: widget.NewSelect([]string{}, nil),
-		noteOnTpl: widget.NewEntry(), noteOffTpl: widget.NewEntry(), pbTpl: widget.NewEntry(),
+		outMode: widget.NewSelect(outModes(), nil), sockPath: widget.NewEntry(),
+		execCmd: widget.NewEntry(), execEach: widget.NewCheck("per message", nil),
+		serialPort: widget.NewSelectEntry(serialPorts()), serialBaud: widget.NewEntry(),
+		batchSize: widget.NewEntry(), batchMs: widget.NewEntry(), framing: widget.NewSelect(framings, nil), encoding: widget.NewSelect(encodings, nil),
+		maxUDP: widget.NewEntry(), onOversize: widget.NewSelect(oversizeModes, nil), glideMs: widget.NewEntry(), glideHz: widget.NewEntry(),
+		rateLimit: widget.NewEntry(), rateBurst: widget.NewEntry(), rateMode: widget.NewSelect(rateModes, nil), delayMs: widget.NewEntry(), jitterMs: widget.NewEntry(),
+		onError: widget.NewSelect([]string{"send error text", "suppress send"}, nil), deadband: widget.NewEntry(),
+		chanNumbers: widget.NewSelect([]string{"1-16", "0-15"}, nil), stampMode: widget.NewSelect(stampModes, nil),
+		byteMode: widget.NewSelect(byteModes, nil),
+		midiSelect: widget.NewSelect([]string{}, nil), midiOutSel: widget.NewSelect([]string{noMidiOut}, nil), driverSel: widget.NewSelect(driverNames(), nil),
+		virtualIn: widget.NewCheck("create virtual input", nil), virtualName: widget.NewEntry(),
+		retryCount: widget.NewEntry(), retryMs: widget.NewEntry(), inBuf: widget.NewEntry(),
+		noteOnTpl: widget.NewEntry(), noteOffTpl: widget.NewEntry(), pbTpl: widget.NewEntry(), bendRange: widget.NewEntry(), bendNote: widget.NewCheck("as last note", nil),
+		mergeOff: widget.NewCheck("as note-on v0", nil), latch: widget.NewCheck("latch", nil),
+		atTpl: widget.NewEntry(), polyAtTpl: widget.NewEntry(),
+		velCurve: widget.NewSelect(curves, nil), relCurve: widget.NewSelect(releaseCurves, nil), curveAt: widget.NewCheck("also pressure", nil),
+		ccTpl: widget.NewEntry(), modeTpl: widget.NewEntry(), ccMapEntry: widget.NewMultiLineEntry(), invertEntry: widget.NewEntry(), adjEntry: widget.NewMultiLineEntry(),
+		macroEntry: widget.NewMultiLineEntry(), watchPath: widget.NewEntry(),
+		mpeCheck: widget.NewCheck("MPE", nil), mpeMaster: widget.NewSelect([]string{"lower (ch 1)", "upper (ch 16)"}, nil),
+		mpeMembers: widget.NewEntry(), mpeTpl: widget.NewEntry(),
+		mtcTpl: widget.NewEntry(), mtcMode: widget.NewSelect([]string{"every frame", "every quarter-frame"}, nil),
+		scriptMode: widget.NewCheck("script", nil), scriptEntry: widget.NewMultiLineEntry(), scriptMs: widget.NewEntry(),
 		manualEntry: widget.NewEntry(), indicator: canvas.NewCircle(color.NRGBA{80, 80, 80, 255}),
+		connDot: canvas.NewCircle(connColors[connIdle]), dialMs: widget.NewEntry(),
+		outDot: canvas.NewCircle(color.NRGBA{80, 80, 80, 255}), piano: newKeyboard(), colorIn: widget.NewEntry(), colorOut: widget.NewEntry(),
+		strictVars: widget.NewCheck("strict: unknown $names block the send", nil),
+		statusLabel: widget.NewLabel("not connected"), collapse: widget.NewCheck("collapse repeats", nil),
+		trigEntry: widget.NewEntry(), onTop: widget.NewCheck("always on top", nil), themeMode: widget.NewSelect(themeModes, nil), autoPause: widget.NewCheck("pause on trigger", nil),
+		clearOnConn: widget.NewCheck("clear logs on connect", nil),
+		repeats: map[*widget.Entry]repeat{}, logShift: map[*widget.Entry]int{}, repaintMs: widget.NewEntry(), logs: map[*widget.Entry]*logRing{}, logLines: widget.NewEntry(), typePorts: widget.NewEntry(), clampEntry: widget.NewEntry(), seqTags: widget.NewCheck("tag #seq", nil),
+		lfoRate: widget.NewSelect(lfoRates, nil), lfoShape: widget.NewSelect(lfoShapes, nil), lfoUni: widget.NewCheck("0..1", nil), solo: -1, bpm: defaultBPM, clock: time.Now,
 	}
+	s.monitor = s.newMonitor()
+	s.tplOff = map[string]bool{}
+	s.tplOn = s.newTplToggles()
+	s.logGroup, s.sendGroup = s.newKindGroup(&s.logKinds), s.newKindGroup(&s.sendKinds)
 	s.indicator.Resize(fyne.NewSize(14, 14))
+	s.colorIn.SetPlaceHolder("midi in #00ff00"); s.colorOut.SetPlaceHolder("output #00a0ff")
+	for _, e := range []*widget.Entry{s.colorIn, s.colorOut} {
+		e.Validator = func(t string) error { if t == "" { return nil }; _, err := parseColor(t); return err }
+	}
 	s.midiLog.TextStyle = fyne.TextStyle{Monospace: true}
 	s.udpLog.TextStyle = fyne.TextStyle{Monospace: true}
+	s.echoLog.TextStyle = fyne.TextStyle{Monospace: true}
+	s.echoPort.SetPlaceHolder("local port, e.g. 60440")
 
 	s.addrEntry.SetText("127.0.0.1"); s.portEntry.SetText("60440")
+	s.virtualName.SetText("sk8-bridge-1")
+	s.dialMs.SetPlaceHolder("ms (3000)")
+	s.dialMs.Validator = func(t string) error { _, err := parseNonNegative("dial timeout", t); return err }
+	s.retryCount.SetPlaceHolder("attempts if busy, 0 = off"); s.retryMs.SetPlaceHolder("first delay ms (500)")
+	s.retryCount.Validator = func(t string) error { _, err := parseNonNegative("retries", t); return err }
+	s.retryMs.Validator = func(t string) error { _, err := parseNonNegative("retry delay", t); return err }
+	s.framing.SetSelected("raw"); s.encoding.SetSelected("utf-8")
+	s.outMode.SetSelected("udp"); s.onError.SetSelected("send error text"); s.chanNumbers.SetSelected("1-16")
+	s.sockPath.SetPlaceHolder("/tmp/skred.sock")
+	s.execCmd.SetPlaceHolder("command reading payloads on stdin")
+	s.serialPort.SetPlaceHolder("/dev/ttyACM0 or COM3"); s.serialBaud.SetText("115200")
+	s.serialBaud.Validator = func(t string) error { _, err := parseBaud(t); return err }
+	s.deadband.SetPlaceHolder("cc/bend steps to ignore, 0 = off")
+	s.deadband.Validator = func(t string) error { _, err := parseNonNegative("deadband", t); return err }
+	s.batchSize.SetPlaceHolder("bytes, 0 = off"); s.batchMs.SetPlaceHolder("ms, 0 = off")
+	s.batchSize.Validator = func(t string) error { _, err := parseNonNegative("batch size", t); return err }
+	s.batchMs.Validator = func(t string) error { _, err := parseNonNegative("batch interval", t); return err }
+	s.maxUDP.SetPlaceHolder("max datagram bytes (1400)"); s.onOversize.SetSelected("drop")
+	s.maxUDP.Validator = func(t string) error { _, err := parseNonNegative("max datagram", t); return err }
+	s.logLines.SetPlaceHolder(fmt.Sprintf("lines kept per log, %d-%d (%d)", minLogLines, maxLogLines, defaultLogLines))
+	s.logLines.Validator = func(t string) error { _, err := parseLogLines(t); return err }
+	s.repaintMs.SetPlaceHolder("repaint logs and lights at most every ms (0 = on every change)")
+	s.repaintMs.Validator = func(t string) error { _, err := parseNonNegative("repaint interval", t); return err }
+	s.repaintMs.OnChanged = func(t string) {
+		if ms, err := parseNonNegative("repaint interval", t); err == nil { s.mu.Lock(); s.repaint = time.Duration(ms) * time.Millisecond; s.mu.Unlock() }
+	}
+	s.trigEntry.SetPlaceHolder("e.g. cc ch1 7=127, note-on 60, pitch-bend >=4000")
+	s.trigEntry.Validator = func(t string) error { _, err := parseTrigger(t); return err }
+	s.typePorts.SetPlaceHolder("per-type ports, e.g. note-on: 9001, cc: 9002")
+	s.typePorts.Validator = func(t string) error { _, err := parsePorts(t); return err }
+	s.inBuf.SetPlaceHolder("messages, 0 = handle in the driver callback")
+	s.inBuf.Validator = func(t string) error { _, err := parseNonNegative("input buffer", t); return err }
+	s.glideMs.SetPlaceHolder("glide ms, 0 = off"); s.glideHz.SetPlaceHolder("updates/s (60)")
+	s.glideMs.Validator = func(t string) error { _, err := parseNonNegative("glide time", t); return err }
+	s.glideHz.Validator = func(t string) error { _, err := parseNonNegative("glide rate", t); return err }
+	s.rateLimit.SetPlaceHolder("msgs/s, 0 = off"); s.rateBurst.SetPlaceHolder("burst (= rate)"); s.rateMode.SetSelected("drop")
+	s.rateLimit.Validator = func(t string) error { _, err := parseNonNegative("rate limit", t); return err }
+	s.rateBurst.Validator = func(t string) error { _, err := parseNonNegative("rate burst", t); return err }
+	s.delayMs.SetPlaceHolder("delay ms, 0 = off"); s.jitterMs.SetPlaceHolder("+ random ms, 0 = off")
+	s.delayMs.Validator = func(t string) error { _, err := parseNonNegative("output delay", t); return err }
+	s.jitterMs.Validator = func(t string) error { _, err := parseNonNegative("delay jitter", t); return err }
 	s.noteOnTpl.SetText("v$c n$n l{$v/127}"); s.noteOffTpl.SetText("v$c n$n l0")
-	s.pbTpl.SetText("v$c p{($p-8192)/8192}")
+	s.pbTpl.SetText("v$c p{$b/8192}")
+	s.atTpl.SetPlaceHolder("e.g. v$c z{$pn} (empty = not sent)")
+	s.polyAtTpl.SetPlaceHolder("e.g. v$c n$n z{$pn} (empty = not sent)")
+	s.velCurve.SetSelected("linear"); s.relCurve.SetSelected(releaseCurves[0])
+	s.bendRange.SetPlaceHolder("± semitones (2)")
+	s.bendRange.Validator = func(t string) error { _, err := parseBendRange(t); return err }
+	s.stampMode.SetSelected("clock"); s.byteMode.SetSelected("hex"); s.midiOutSel.SetSelected(noMidiOut)
+	s.collapse.SetChecked(true)
+	if d := drivers.Get(); d != nil { s.driverSel.SetSelected(d.String()) }
+	s.ccTpl.SetPlaceHolder("e.g. v$c c$n l{$v/127} (empty = not sent)")
+	s.modeTpl.SetPlaceHolder("cc 120-127, e.g. v$c $mode (empty = use cc)")
+	s.ccMapEntry.SetPlaceHolder("1: v$c m{$v/127}\n7: v$c a{$v/127}")
+	s.ccMapEntry.SetMinRowsVisible(3)
+	s.mpeMaster.SetSelected("lower (ch 1)"); s.mpeMembers.SetText("15")
+	s.mpeMembers.Validator = func(text string) error { _, err := parseMembers(text); return err }
+	s.mpeTpl.SetPlaceHolder("e.g. v$c n$n b{$bend} z{$pressure} y{$slide}")
+	s.mtcTpl.SetPlaceHolder("e.g. tc $tc (empty = not sent)"); s.mtcMode.SetSelected("every frame")
+	s.invertEntry.SetPlaceHolder("controllers to flip, e.g. 7, 11, pb")
+	s.invertEntry.Validator = func(text string) error { _, err := parseInvert(text); return err }
+	s.invertEntry.OnChanged = func(text string) {
+		if m, err := parseInvert(text); err == nil { s.mu.Lock(); s.invert = m; s.mu.Unlock() }
+	}
+	s.ccMapEntry.Validator = func(text string) error { _, err := parseCCMap(text); return err }
+	s.watchPath.SetPlaceHolder(".sk8 file to reload on change")
+	s.macroEntry.SetPlaceHolder("@lvl = {$v/127}")
+	s.macroEntry.SetMinRowsVisible(2)
+	s.macroEntry.Validator = func(text string) error { _, err := tpl.ParseMacros(text); return err }
+	s.macroEntry.OnChanged = func(text string) {
+		if m, err := tpl.ParseMacros(text); err == nil { s.mu.Lock(); s.macros = m; s.mu.Unlock() }
+	}
+	s.lfoRate.SetSelected("1 bar"); s.lfoShape.SetSelected("sine")
+	s.themeMode.SetSelected("auto")
+	s.clampEntry.SetPlaceHolder("min,max for every {} result, e.g. 0,127 (empty = off)")
+	s.clampEntry.Validator = func(text string) error { _, err := tpl.ParseRange(text); return err }
+	s.clampEntry.OnChanged = func(text string) {
+		if r, err := tpl.ParseRange(text); err == nil { s.mu.Lock(); s.clamp = r; s.mu.Unlock() }
+	}
+	s.adjEntry.SetPlaceHolder("channel: transpose [velocity], e.g.\n1: -12\n2: +7 +10")
+	s.adjEntry.SetMinRowsVisible(2)
+	s.adjEntry.Validator = func(text string) error { _, err := parseChanAdjust(text, s.channelBase()); return err }
+	s.adjEntry.OnChanged = func(text string) {
+		if m, err := parseChanAdjust(text, s.channelBase()); err == nil { s.mu.Lock(); s.chanAdj = m; s.mu.Unlock() }
+	}
+	s.chanNumbers.OnChanged = func(string) { s.renumber() }
+	s.ccMapEntry.OnChanged = func(text string) {
+		if m, err := parseCCMap(text); err == nil { s.mu.Lock(); s.ccTpls = m; s.mu.Unlock() }
+	}
 	s.manualEntry.SetPlaceHolder("Manual UDP Command...")
+	s.scriptEntry.SetPlaceHolder("one command per line, ;wait 100 to pause")
+	s.scriptEntry.SetMinRowsVisible(4)
+	s.scriptMs.SetPlaceHolder("delay ms (0)")
+	s.scriptMs.Validator = func(t string) error { _, err := parseNonNegative("script delay", t); return err }
+	s.midiSelect.OnChanged = func(name string) { if name != "" { s.wantIn = name } }
+	return s
+}
+
+func main() {
+	profile := flag.String("profile", "", "instance name; keeps a separate set of saved settings")
+	driverName := flag.String("driver", "", "MIDI driver to use, one of: "+strings.Join(driverNames(), ", "))
+	logFormat := flag.String("log-format", "text", "also print each MIDI event to stdout as one JSON object per line with json")
+	dryRunPath := flag.String("dryrun", "", "render a canned MIDI sequence through this .sk8 preset, print the payloads and exit")
+	midiStdin := flag.String("midi-stdin", "", "with -dryrun, render MIDI read from stdin instead of the canned sequence, as "+strings.Join(streamFormats, " or ")+" bytes")
+	flag.Parse()
+	if *midiStdin != "" && (*dryRunPath == "" || !slices.Contains(streamFormats, *midiStdin)) {
+		fmt.Fprintf(os.Stderr, "-midi-stdin needs -dryrun and one of: %s\n", strings.Join(streamFormats, ", ")); os.Exit(2)
+	}
+	if *dryRunPath != "" {
+		source := cannedSequence
+		if *midiStdin != "" { source = streamSource(os.Stdin, *midiStdin) }
+		app.New() // the widgets holding the settings need an app, though no window is opened
+		os.Exit(dryRun(*dryRunPath, source, os.Stdout, os.Stderr))
+	}
+	if !slices.Contains(logFormats, *logFormat) {
+		fmt.Fprintf(os.Stderr, "unknown log format %q, use one of: %s\n", *logFormat, strings.Join(logFormats, ", ")); os.Exit(2)
+	}
+	id, err := appID(*profile)
+	if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+	title := "midi-sk8 " + version
+	if *profile != "" { title += " [" + *profile + "]" }
+
+	a := app.NewWithID(id)
+	a.Settings().SetTheme(customTheme{theme.LightTheme()})
+	w := a.NewWindow(title)
+	w.SetIcon(fyne.NewStaticResource("icon.png", iconBytes))
+
+	s := newAppState()
+	if *logFormat == "json" { s.jsonLog = json.NewEncoder(os.Stdout) }
+	s.prefs = a.Preferences()
+	s.loadPrefs(s.prefs)
+	s.setWatch(s.watchPath.Text)
+	s.watchPath.OnChanged = s.setWatch
+	s.setTrigger(s.trigEntry.Text)
+	s.trigEntry.OnChanged = s.setTrigger
+	// latched notes then end on their next real note-off, so a later latch starts afresh
+	s.latch.OnChanged = func(bool) { s.mu.Lock(); s.latched = nil; s.mu.Unlock() }
+	if used := s.applyEnv(os.LookupEnv); len(used) > 0 { s.appendLog(s.midiLog, "Settings from environment: "+strings.Join(used, ", ")) }
+	if *driverName == "" { *driverName = os.Getenv("SK8_DRIVER") }
+	if *driverName != "" {
+		if _, ok := drivers.REGISTRY[*driverName]; !ok {
+			fmt.Fprintf(os.Stderr, "unknown MIDI driver %q, this build has: %s\n", *driverName, strings.Join(driverNames(), ", ")); os.Exit(2)
+		}
+		s.driverSel.SetSelected(*driverName)
+	}
+	if len(s.driverSel.Options) < 2 { s.driverSel.Disable() }
+	s.driverChanged()
+
+	var startBtn *widget.Button
+	startBtn = widget.NewButtonWithIcon("Connect", theme.CheckButtonCheckedIcon(), func() { s.startConnect(startBtn) })
 
 	// MIDI Port Discovery
+	offered := false
+	savedOut := s.prefs.String("midi-out")
 	refreshPorts := func() {
-		var names []string
-		for _, port := range midi.GetInPorts() {
-			names = append(names, port.String())
-		}
-		s.midiSelect.Options = names
-		if len(names) > 0 && s.midiSelect.Selected == "" {
-			s.midiSelect.SetSelected(names[0])
-		}
-		s.midiSelect.Refresh()
+		names := inLabels(s.inPorts())
+		s.midiSelect.SetOptions(names)
+		s.selectIn(names)
+		outs := []string{noMidiOut}
+		for _, port := range s.outPorts() { outs = append(outs, port.String()) }
+		// the saved output is only listed once the driver is asked, so restore it on the first pass
+		want := s.midiOutSel.Selected
+		if savedOut != "" { want, savedOut = savedOut, "" }
+		s.midiOutSel.SetOptions(outs)
+		if !slices.Contains(outs, want) { want = noMidiOut }
+		s.midiOutSel.SetSelected(want)
+		s.checkPorts(startBtn, w, !offered)
+		offered = offered || len(names) == 0
 	}
 	refreshPorts()
+	s.driverSel.OnChanged = func(string) {
+		s.driverChanged()
+		s.midiSelect.ClearSelected()
+		refreshPorts()
+	}
 
 	// Configuration Forms
 	configForm := widget.NewForm(
 		widget.NewFormItem("udp-addr", s.addrEntry),
 		widget.NewFormItem("udp-port", s.portEntry),
+		widget.NewFormItem("ports", s.typePorts),
+		widget.NewFormItem("dial-timeout", s.dialMs),
+		widget.NewFormItem("output", s.outMode),
+		widget.NewFormItem("socket", s.sockPath),
+		widget.NewFormItem("exec", container.NewBorder(nil, nil, nil, s.execEach, s.execCmd)),
+		widget.NewFormItem("serial", container.NewBorder(nil, nil, nil, container.NewHBox(s.serialBaud,
+			widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() { s.serialPort.SetOptions(serialPorts()) })), s.serialPort)),
+		widget.NewFormItem("framing", container.NewGridWithColumns(2, s.framing, s.encoding)),
+		widget.NewFormItem("batch", container.NewGridWithColumns(2, s.batchSize, s.batchMs)),
+		widget.NewFormItem("rate-limit", container.NewGridWithColumns(3, s.rateLimit, s.rateBurst, s.rateMode)),
+		widget.NewFormItem("delay", container.NewGridWithColumns(2, s.delayMs, s.jitterMs)),
+		widget.NewFormItem("on-error", s.onError),
+		widget.NewFormItem("variables", s.strictVars),
+		widget.NewFormItem("oversize", container.NewGridWithColumns(2, s.maxUDP, s.onOversize)),
+		widget.NewFormItem("deadband", s.deadband),
+		widget.NewFormItem("glide", container.NewGridWithColumns(2, s.glideMs, s.glideHz)),
+		widget.NewFormItem("channels", s.chanNumbers),
+		widget.NewFormItem("timestamps", s.stampMode),
+		widget.NewFormItem("bytes", s.byteMode),
+		widget.NewFormItem("repeats", s.collapse),
+		widget.NewFormItem("log-lines", s.logLines),
+		widget.NewFormItem("repaint", s.repaintMs),
+		widget.NewFormItem("history", s.clearOnConn),
+		widget.NewFormItem("trigger", container.NewBorder(nil, nil, nil, s.autoPause, s.trigEntry)),
+		widget.NewFormItem("colors", container.NewGridWithColumns(2, s.colorIn, s.colorOut)),
+		widget.NewFormItem("log", s.logGroup),
+		widget.NewFormItem("send", s.sendGroup),
 		widget.NewFormItem("midi-in", container.NewBorder(nil, nil, nil, 
 			widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), refreshPorts), s.midiSelect)),
+		widget.NewFormItem("midi-out", s.midiOutSel),
+		widget.NewFormItem("driver", s.driverSel),
+		widget.NewFormItem("virtual-in", container.NewBorder(nil, nil, s.virtualIn, nil, s.virtualName)),
+		widget.NewFormItem("midi-retry", container.NewGridWithColumns(2, s.retryCount, s.retryMs)),
+		widget.NewFormItem("midi-buffer", s.inBuf),
 	)
 	configForm.Hide()
 
+	on := func(key string, o fyne.CanvasObject) fyne.CanvasObject { return container.NewBorder(nil, nil, s.tplOn[key], s.newTestButton(key), o) }
 	tplForm := widget.NewForm(
-		widget.NewFormItem("note-on", s.noteOnTpl),
-		widget.NewFormItem("note-off", s.noteOffTpl),
-		widget.NewFormItem("pitch-bend", s.pbTpl),
+		widget.NewFormItem("watch", s.watchPath),
+		widget.NewFormItem("macros", s.macroEntry),
+		widget.NewFormItem("clamp", s.clampEntry),
+		widget.NewFormItem("lfo", container.NewGridWithColumns(3, s.lfoRate, s.lfoShape, s.lfoUni)),
+		widget.NewFormItem("note-on", on("note-on", container.NewBorder(nil, nil, nil, s.latch, s.noteOnTpl))),
+		widget.NewFormItem("note-off", on("note-off", container.NewBorder(nil, nil, nil, s.mergeOff, s.noteOffTpl))),
+		widget.NewFormItem("pitch-bend", on("pitch-bend", container.NewBorder(nil, nil, nil, container.NewHBox(s.bendNote, s.bendRange), s.pbTpl))),
+		widget.NewFormItem("aftertouch", on("aftertouch", s.atTpl)),
+		widget.NewFormItem("poly-at", on("poly-at", s.polyAtTpl)),
+		widget.NewFormItem("curve", container.NewHBox(s.velCurve, s.curveAt, widget.NewLabel("release"), s.relCurve)),
+		widget.NewFormItem("cc", on("cc", s.ccTpl)),
+		widget.NewFormItem("cc-map", s.ccMapEntry),
+		widget.NewFormItem("chan-mode", on("chan-mode", s.modeTpl)),
+		widget.NewFormItem("invert", s.invertEntry),
+		widget.NewFormItem("transpose", s.adjEntry),
+		widget.NewFormItem("mpe-zone", container.NewHBox(s.mpeCheck, s.mpeMaster, widget.NewLabel("members"), s.mpeMembers)),
+		widget.NewFormItem("mpe-expr", on("mpe-expr", s.mpeTpl)),
+		widget.NewFormItem("mtc", on("mtc", container.NewBorder(nil, nil, nil, s.mtcMode, s.mtcTpl))),
 	)
 	tplForm.Hide()
 
-	themeBtn := widget.NewButtonWithIcon("", theme.ColorPaletteIcon(), func() {
-		if s.isDark { a.Settings().SetTheme(customTheme{theme.LightTheme()}); s.isDark = false
-		} else { a.Settings().SetTheme(customTheme{theme.DarkTheme()}); s.isDark = true }
-	})
+	s.followTheme(a)
+	themeBtn := widget.NewButtonWithIcon("", theme.ColorPaletteIcon(), s.toggleTheme)
+
+	exportBtn := widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), func() { s.showExport(w) })
+	importBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() { s.showImport(w) })
+	fetchBtn := widget.NewButtonWithIcon("", theme.DownloadIcon(), func() { s.showFetch(w) })
+	sessionBtn := widget.NewButtonWithIcon("", theme.DocumentIcon(), func() { s.showSessionExport(w) })
 
 	settingsToggle := widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
 		if configForm.Hidden { configForm.Show(); tplForm.Show() } else { configForm.Hide(); tplForm.Hide() }
 	})
 
-	pauseBtn := widget.NewButtonWithIcon("", theme.MediaPauseIcon(), func() { s.isPaused = !s.isPaused })
-	clearBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() { s.midiLog.SetText(""); s.udpLog.SetText("") })
+	togglePause := func() { s.isPaused = !s.isPaused }
+	var armBtn *widget.Button
+	armBtn = widget.NewButton("armed", func() {
+		s.armed = !s.armed
+		if s.armed { armBtn.SetText("armed"); armBtn.Importance = widget.DangerImportance
+		} else { armBtn.SetText("disarmed"); armBtn.Importance = widget.MediumImportance }
+		armBtn.Refresh()
+	})
+	s.armed, armBtn.Importance = true, widget.DangerImportance
+	pauseBtn := widget.NewButtonWithIcon("", theme.MediaPauseIcon(), togglePause)
+	var followBtn *widget.Button
+	followBtn = widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
+		s.follow = !s.follow
+		if s.follow { followBtn.Importance = widget.HighImportance } else { followBtn.Importance = widget.MediumImportance }
+		followBtn.Refresh()
+	})
+	s.follow, followBtn.Importance = true, widget.HighImportance
+	repeatBtn := widget.NewButtonWithIcon("", theme.MediaReplayIcon(), s.repeatLast)
+	clearBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), s.clearLogs)
 
 	sendManual := func() {
-		if s.manualEntry.Text != "" && s.udpConn != nil {
-			s.udpConn.Write([]byte(s.manualEntry.Text))
-			fyne.Do(func() { s.udpLog.SetText(s.udpLog.Text + "> " + s.manualEntry.Text + "\n"); s.manualEntry.SetText("") })
-			s.flash()
+		if s.scriptMode.Checked { if s.udpOut != nil { s.runScript() }; return }
+		if s.manualEntry.Text != "" && s.udpOut != nil {
+			if err := s.send(s.manualEntry.Text); err != nil { s.appendLog(s.udpLog, "! "+err.Error()); return }
+			s.appendLog(s.udpLog, "> "+s.manualEntry.Text)
+			s.manualEntry.SetText("")
+			s.flashOut()
 		}
 	}
 	s.manualEntry.OnSubmitted = func(_ string) { sendManual() }
-	manualBox := container.NewBorder(nil, nil, nil, widget.NewButtonWithIcon("", theme.MailSendIcon(), sendManual), s.manualEntry)
-
-	startBtn := widget.NewButtonWithIcon("Connect", theme.CheckButtonCheckedIcon(), func() {
-		if s.stopMidi != nil { s.stopMidi() }
-		
-		conn, err := net.Dial("udp", s.addrEntry.Text+":"+s.portEntry.Text)
-		if err != nil { return }
-		s.udpConn = conn
-
-		var in drivers.In
-		// 1. Try to find the port selected in the dropdown
-		for _, p := range midi.GetInPorts() {
-			if p.String() == s.midiSelect.Selected {
-				in = p
-				break
-			}
-		}
-
-		// 2. Fallback: On Linux, if it's a specific name, try creating a Virtual Port
-		if in == nil {
-			drv := drivers.Get()
-			if vDrv, ok := drv.(interface{ OpenVirtualIn(string) (drivers.In, error) }); ok {
-				in, _ = vDrv.OpenVirtualIn("sk8-bridge-1")
-			}
-		}
+	scriptBox := container.NewBorder(nil, s.scriptMs, nil, nil, s.scriptEntry)
+	scriptBox.Hide()
+	s.scriptMode.OnChanged = func(on bool) {
+		if on { s.manualEntry.Hide(); scriptBox.Show() } else { s.cancelScript(); scriptBox.Hide(); s.manualEntry.Show() }
+	}
+	manualBox := container.NewBorder(nil, nil, s.scriptMode, widget.NewButtonWithIcon("", theme.MailSendIcon(), sendManual),
+		container.NewStack(s.manualEntry, scriptBox))
 
-		if in == nil {
-			s.midiLog.SetText("Error: Port not found. Please refresh and select a valid input.\n")
+	// the minimal view swaps the content for a control strip; the full layout is kept as
+	// it was, including its window size, for switching back
+	var full, strip fyne.CanvasObject
+	var fullSize fyne.Size
+	setMinimal := func(on bool) {
+		s.minimal = on
+		if on {
+			if w.Content() == full { fullSize = w.Canvas().Size() }
+			w.SetContent(strip); w.Resize(strip.MinSize())
 			return
 		}
+		w.SetContent(full)
+		if fullSize.IsZero() { fullSize = fyne.NewSize(640, 720) }
+		w.Resize(fullSize)
+	}
+	toggleMinimal := func() { setMinimal(!s.minimal) }
 
-		s.midiLog.SetText(fmt.Sprintf("Listening to: %s\n", in.String()))
-
-		stop, _ := midi.ListenTo(in, func(msg midi.Message, ts int32) {
-			var ch, key, vel uint8
-			var bend int16
-			var abs uint16
-			var out string
-			switch {
-			case msg.GetNoteOn(&ch, &key, &vel): out = s.transform(s.noteOnTpl.Text, ch, key, vel, 0)
-			case msg.GetNoteOff(&ch, &key, &vel): out = s.transform(s.noteOffTpl.Text, ch, key, vel, 0)
-			case msg.GetPitchBend(&ch, &bend, &abs): out = s.transform(s.pbTpl.Text, ch, 0, uint8(abs>>7), abs)
-			}
-			if out != "" && s.udpConn != nil { s.udpConn.Write([]byte(out)) }
-			s.flash()
-			if !s.isPaused {
-				hex := ""
-				for _, b := range msg.Bytes() { hex += fmt.Sprintf("%02X ", b) }
-				fyne.Do(func() {
-					if len(s.midiLog.Text) > 2000 { s.midiLog.SetText(s.midiLog.Text[1000:]) }
-					s.midiLog.SetText(s.midiLog.Text + strings.TrimSpace(hex) + "\n")
-					s.midiLog.CursorRow = len(strings.Split(s.midiLog.Text, "\n"))
-					if out != "" {
-						if len(s.udpLog.Text) > 2000 { s.udpLog.SetText(s.udpLog.Text[1000:]) }
-						s.udpLog.SetText(s.udpLog.Text + out + "\n")
-						s.udpLog.CursorRow = len(strings.Split(s.udpLog.Text, "\n"))
-					}
-				})
-			}
-		})
-		s.stopMidi = stop
-	})
+	keys := []shortcut{
+		{fyne.KeyReturn, "connect", startBtn.OnTapped}, {fyne.KeyP, "pause log", togglePause}, {fyne.KeyL, "clear logs", s.clearLogs},
+		{fyne.KeyPeriod, "panic (release held notes)", s.panicNotes}, {fyne.KeyT, "toggle theme", s.toggleTheme},
+		{fyne.KeyM, "minimal view", toggleMinimal}, {fyne.KeyS, "save preset (keeps a .bak)", func() { s.savePreset(w) }},
+		{fyne.KeyN, "toggle note-off as note-on", s.toggleMerge}, {fyne.KeyK, "add a marker to the logs", func() { s.showMarker(w) }},
+	}
+	addShortcuts(w, keys)
+	helpBtn := widget.NewButtonWithIcon("", theme.HelpIcon(), func() { showShortcuts(w, keys) })
+	statsBtn := widget.NewButtonWithIcon("", theme.ListIcon(), s.showStats)
 
-	indicatorBox := container.NewStack(container.NewGridWrap(fyne.NewSize(14, 14), s.indicator))
-	header := container.NewBorder(nil, nil, container.NewHBox(themeBtn, settingsToggle), container.NewHBox(indicatorBox, pauseBtn, clearBtn), widget.NewLabelWithStyle("MIDI-SK8 " + version, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}))
+	indicatorBox := container.NewHBox(container.NewGridWrap(fyne.NewSize(14, 14), s.connDot), container.NewGridWrap(fyne.NewSize(14, 14), s.indicator), container.NewGridWrap(fyne.NewSize(14, 14), s.outDot))
+	minimalBtn := widget.NewButtonWithIcon("", theme.ContentRemoveIcon(), toggleMinimal)
+	header := container.NewBorder(nil, nil, container.NewHBox(themeBtn, settingsToggle, exportBtn, importBtn, fetchBtn, sessionBtn, statsBtn, helpBtn, minimalBtn), container.NewHBox(indicatorBox, s.newTapButton(), s.newSoloSelect(), armBtn, repeatBtn, pauseBtn, followBtn, clearBtn), widget.NewLabelWithStyle("MIDI-SK8 " + version, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}))
 	
+	s.monitor.Hide()
+	keysPane, keysBtn := s.newKeyboardPane()
+	var viewBtn *widget.Button
+	viewBtn = widget.NewButtonWithIcon("decoded", theme.GridIcon(), func() {
+		if s.monitor.Hidden { s.monitor.Show(); s.midiLog.Hide(); viewBtn.SetText("raw")
+		} else { s.monitor.Hide(); s.midiLog.Show(); viewBtn.SetText("decoded") }
+	})
+	viewBtn.Importance = widget.LowImportance
+	midiHeader := container.NewBorder(nil, nil, widget.NewLabelWithStyle("MIDI IN", 0, fyne.TextStyle{Italic: true}), container.NewHBox(widget.NewButtonWithIcon("mark", theme.ContentAddIcon(), func() { s.showMarker(w) }), s.newCaptureBar(), keysBtn, viewBtn))
+	echoPane := container.NewBorder(widget.NewLabelWithStyle("UDP ECHO", 0, fyne.TextStyle{Italic: true}), nil, nil, nil, s.echoLog)
+	echoPane.Hide()
 	logStack := container.NewVSplit(
-		container.NewBorder(widget.NewLabelWithStyle("MIDI IN", 0, fyne.TextStyle{Italic: true}), nil, nil, nil, s.midiLog),
-		container.NewBorder(widget.NewLabelWithStyle("UDP OUT", 0, fyne.TextStyle{Italic: true}), nil, nil, nil, s.udpLog),
+		container.NewBorder(midiHeader, keysPane, nil, nil, container.NewStack(s.midiLog, s.monitor)),
+		container.NewVSplit(
+			container.NewBorder(widget.NewLabelWithStyle("UDP OUT", 0, fyne.TextStyle{Italic: true}), nil, nil, nil, s.udpLog),
+			echoPane,
+		),
 	)
+	configForm.Append("outputs", s.newSinkEditor(s.prefs.StringList("sinks")))
+	configForm.Append("udp-echo", container.NewBorder(nil, nil, nil, container.NewHBox(s.seqTags, s.newEchoToggle(echoPane)), s.echoPort))
 	logStack.SetOffset(0.5)
 
-	topArea := container.NewVBox(header, configForm, tplForm, startBtn, manualBox)
-	w.SetContent(container.NewBorder(topArea, nil, nil, nil, logStack))
+	s.statusLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	s.statusLabel.Truncation = fyne.TextTruncateEllipsis
+	reconnectBtn := widget.NewButtonWithIcon("reconnect all", theme.ViewRefreshIcon(), func() { s.reconnectAll(startBtn) })
+	teardownBtn := widget.NewButtonWithIcon("tear down all", theme.CancelIcon(), s.teardownAll)
+	topArea := container.NewVBox(header, configForm, tplForm, container.NewBorder(nil, nil, nil, container.NewHBox(reconnectBtn, teardownBtn), startBtn), s.statusLabel, manualBox)
+	full = container.NewBorder(topArea, nil, nil, nil, logStack)
+	strip = container.NewHBox(
+		container.NewHBox(container.NewGridWrap(fyne.NewSize(14, 14), s.connDot), container.NewGridWrap(fyne.NewSize(14, 14), s.indicator), container.NewGridWrap(fyne.NewSize(14, 14), s.outDot)),
+		widget.NewButtonWithIcon("Connect", theme.CheckButtonCheckedIcon(), func() { s.startConnect(startBtn) }),
+		widget.NewButtonWithIcon("", theme.MediaPauseIcon(), togglePause),
+		widget.NewButton("Panic", s.panicNotes),
+		widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), toggleMinimal),
+	)
+	w.SetContent(full)
 	w.Resize(fyne.NewSize(640, 720))
+	if s.minimal { setMinimal(true) }
+	w.SetOnDropped(s.dropPresets(w))
+	configForm.Append("theme", s.themeMode)
+	if canPin() {
+		pin := func(on bool) { if err := pinWindow(w, on); err != nil { s.appendLog(s.midiLog, "Error: always on top: "+err.Error()) } }
+		configForm.Append("window", s.onTop)
+		// the window only exists once running, so the saved setting is applied then
+		a.Lifecycle().SetOnStarted(func() { if s.onTop.Checked { pin(true) }; s.onTop.OnChanged = pin })
+	}
+	w.SetOnClosed(func() { s.savePrefs(s.prefs); s.setWatch(""); s.disconnect(); s.stopEcho(); closeDrivers() })
 	w.ShowAndRun()
 }
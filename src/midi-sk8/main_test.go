@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// TestMain runs the tests under Fyne's headless test app, so widgets and fyne.Do work
+// without a display
+func TestMain(m *testing.M) {
+	test.NewApp()
+	os.Exit(m.Run())
+}
+
+func TestRenderElapsed(t *testing.T) {
+	s := newAppState()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.clock = func() time.Time { return at }
+	if got := s.render("note", "$t $tms", msgVars(0, 60, 100, 0)); got != "0 0" { t.Errorf("not connected: render = %q, want \"0 0\"", got) }
+	s.connectedAt = at
+	at = at.Add(1500 * time.Millisecond)
+	if got := s.render("note", "$t $tms", msgVars(0, 60, 100, 0)); got != "1.5000 1500" { t.Errorf("connected 1.5s: render = %q, want \"1.5000 1500\"", got) }
+}
+
+func TestRenderOnError(t *testing.T) {
+	for _, tc := range []struct{ mode, want, log string }{
+		{"send error text", "x DIV0", ""},
+		{"suppress send", "", "! note-on template not sent: {1/0} -> DIV0\n"},
+	} {
+		s := newAppState()
+		s.repaint = time.Hour
+		s.onError.SetSelected(tc.mode)
+		if got := s.render("note-on", "x {1/0}", msgVars(0, 60, 100, 0)); got != tc.want { t.Errorf("%s: render = %q, want %q", tc.mode, got, tc.want) }
+		if got := s.logRingFor(s.udpLog).text(); got != tc.log { t.Errorf("%s: log %q, want %q", tc.mode, got, tc.log) }
+		if got := s.render("note-on", "x {1/2}", msgVars(0, 60, 100, 0)); got != "x 0.5000" { t.Errorf("%s: render of a good template = %q", tc.mode, got) }
+	}
+}
+
+// testOutput connects s to a recorder as its only sink and arms it, so sends can be
+// read back; the main output keeps the framing chosen before the call
+func testOutput(s *AppState) *recorder {
+	r := &recorder{}
+	s.udpOut, s.armed, s.repaint = newBatcher(r, 0, 0, nil), true, time.Hour
+	s.sinks = []*sink{s.mainSink()}
+	return r
+}
+
+func TestRenderMacros(t *testing.T) {
+	s := newAppState()
+	s.macroEntry.SetText("@lvl = {$v/127}\n@note = n$n @lvl")
+	if got := s.render("note-on", "v$c @note", msgVars(0, 60, 127, 0)); got != "v1 n60 1.0000" { t.Errorf("render = %q", got) }
+	// presets carry the macros
+	loaded := newAppState()
+	loaded.applyPreset(s.snapshot())
+	if got := loaded.render("note-on", "v$c @note", msgVars(0, 60, 127, 0)); got != "v1 n60 1.0000" { t.Errorf("render from a preset = %q", got) }
+	// an invalid edit keeps the last good macros
+	s.macroEntry.SetText("@lvl = @lvl")
+	if got := s.render("note-on", "@lvl", msgVars(0, 60, 127, 0)); got != "1.0000" { t.Errorf("render after a bad edit = %q", got) }
+}
+
+func TestRenderFormatSpecs(t *testing.T) {
+	s := newAppState()
+	for _, tc := range []struct{ text, want string }{
+		{"n$n:03d", "n060"},
+		{"$v:x $v:02X $n:b", "64 64 111100"},
+		{"$vn:.2f", "0.79"},
+		{"$n:", "60:"},
+	} {
+		if got := s.render("note-on", tc.text, s.noteVars(0, 60, 100)); got != tc.want { t.Errorf("render(%q) = %q, want %q", tc.text, got, tc.want) }
+	}
+}
+
+func TestRenderCommentsAndBlanks(t *testing.T) {
+	s := newAppState()
+	for _, tc := range []struct{ text, want string }{
+		{"# level for the mixer\nv$c l$v", "v1 l100"},
+		{"#hash payload $n", "#hash payload 60"}, // one line is never a comment
+		{"# only a comment\n  # and another", ""},
+		{"   ", ""},
+		{"", ""},
+	} {
+		if got := s.render("note-on", tc.text, s.noteVars(0, 60, 100)); got != tc.want { t.Errorf("render(%q) = %q, want %q", tc.text, got, tc.want) }
+	}
+}
+
+func TestRenderStrictVars(t *testing.T) {
+	for _, tc := range []struct {
+		strict    bool
+		text      string
+		want, log string
+	}{
+		{false, "n$n x$x", "n60 x$x", ""},
+		{true, "n$n x$x $yy $x", "", "! note-on template not sent: unknown variable $x, $yy\n"},
+		{true, "n$n v$v t$t b$bpm", "n60 v100 t0 b120", ""},
+		{true, "# $x in a comment\nn$n", "n60", ""},
+		{true, "@lvl", "", "! note-on template not sent: unknown variable $q\n"},
+		{true, "$nx", "60x", ""}, // $n, as Transform reads it
+	} {
+		s := newAppState()
+		s.repaint = time.Hour
+		s.strictVars.SetChecked(tc.strict)
+		s.macroEntry.SetText("@lvl = {$q/127}")
+		if got := s.render("note-on", tc.text, msgVars(0, 60, 100, 0)); got != tc.want { t.Errorf("strict %v: render(%q) = %q, want %q", tc.strict, tc.text, got, tc.want) }
+		if got := s.logRingFor(s.udpLog).text(); got != tc.log { t.Errorf("strict %v: %q logged %q, want %q", tc.strict, tc.text, got, tc.log) }
+	}
+}
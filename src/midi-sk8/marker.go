@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// addMarker puts a timestamped marker line such as "--- 12:00:01.250 verse 2 ---" in
+// both logs, so a capture or exported session can be followed section by section
+func (s *AppState) addMarker(label string) {
+	line := "--- " + s.stamp(time.Now())
+	if label = strings.TrimSpace(label); label != "" { line += " " + label }
+	line += " ---"
+	s.appendLog(s.midiLog, line)
+	s.appendLog(s.udpLog, line)
+}
+
+// showMarker asks for a marker label; an empty one still marks the time
+func (s *AppState) showMarker(w fyne.Window) {
+	e := widget.NewEntry()
+	e.SetPlaceHolder("e.g. verse 2")
+	d := dialog.NewForm("Add marker", "Add", "Cancel", []*widget.FormItem{widget.NewFormItem("label", e)}, func(ok bool) {
+		if ok { s.addMarker(e.Text) }
+	}, w)
+	d.Show()
+	w.Canvas().Focus(e)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// monitorRows bounds the decoded monitor so it never grows with the session
+const monitorRows = 500
+
+var monitorColumns = []string{"time", "type", "ch", "data1", "data2"}
+
+// stampModes are the choices for the monitor's time column
+var stampModes = []string{"clock", "since connect", "since first message"}
+
+// formatStamp shows wall-clock time, or the offset from ref as +0.125s
+func formatStamp(mode string, at, ref time.Time) string {
+	if mode == "clock" || ref.IsZero() { return at.Format("15:04:05.000") }
+	return fmt.Sprintf("+%.3fs", at.Sub(ref).Seconds())
+}
+
+// stamp formats a message time using the configured reference
+func (s *AppState) stamp(at time.Time) string {
+	s.mu.Lock()
+	ref := s.connectedAt
+	if s.stampMode.Selected == "since first message" { ref = s.firstMsgAt }
+	s.mu.Unlock()
+	return formatStamp(s.stampMode.Selected, at, ref)
+}
+
+// monitorRow is one decoded message as shown in the table
+type monitorRow [5]string
+
+// byteModes are the ways the raw log can print message bytes
+var byteModes = []string{"hex", "decimal", "hex + decimal"}
+
+// formatBytes prints bytes as padded hex (90 3C 64), decimal (144  60 100) or both
+func formatBytes(mode string, b []byte) string {
+	var hex, dec []string
+	for _, x := range b { hex = append(hex, fmt.Sprintf("%02X", x)); dec = append(dec, fmt.Sprintf("%3d", x)) }
+	switch mode {
+	case "decimal": return strings.Join(dec, " ")
+	case "hex + decimal": return strings.Join(hex, " ") + " | " + strings.Join(dec, " ")
+	}
+	return strings.Join(hex, " ")
+}
+
+// decodeRow fills the table columns from the message. Channels use the configured numbering.
+// Pitch bend shows the signed bend and the raw 14-bit value instead of its two data bytes.
+func (s *AppState) decodeRow(msg midi.Message, at time.Time) monitorRow {
+	row := monitorRow{s.stamp(at), msg.Type().String()}
+	var ch uint8
+	if msg.GetChannel(&ch) { row[2] = strconv.Itoa(int(ch + s.channelBase())) }
+	var bend int16
+	var abs uint16
+	if msg.GetPitchBend(&ch, &bend, &abs) {
+		row[3], row[4] = fmt.Sprintf("%+d", bend), strconv.Itoa(int(abs))
+		return row
+	}
+	b := msg.Bytes()
+	if len(b) > 1 { row[3] = strconv.Itoa(int(b[1])) }
+	if len(b) > 2 { row[4] = strconv.Itoa(int(b[2])) }
+	return row
+}
+
+// addMonitorRow appends to the decoded monitor from any goroutine
+func (s *AppState) addMonitorRow(row monitorRow) {
+	fyne.Do(func() {
+		s.rows = append(s.rows, row)
+		if len(s.rows) > monitorRows { s.rows = s.rows[len(s.rows)-monitorRows:] }
+		s.monitor.Refresh()
+		if s.follow { s.monitor.ScrollToBottom() }
+	})
+}
+
+// newMonitor builds the decoded monitor table; rows are only touched on the UI goroutine
+func (s *AppState) newMonitor() *widget.Table {
+	t := widget.NewTable(
+		func() (int, int) { return len(s.rows), len(monitorColumns) },
+		func() fyne.CanvasObject { return widget.NewLabelWithStyle("", 0, fyne.TextStyle{Monospace: true}) },
+		func(id widget.TableCellID, o fyne.CanvasObject) { o.(*widget.Label).SetText(s.rows[id.Row][id.Col]) },
+	)
+	t.ShowHeaderRow = true
+	t.CreateHeader = func() fyne.CanvasObject { return widget.NewLabelWithStyle("", 0, fyne.TextStyle{Bold: true}) }
+	t.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Col >= 0 { o.(*widget.Label).SetText(monitorColumns[id.Col]) }
+	}
+	for i, w := range []float32{110, 150, 40, 60, 60} { t.SetColumnWidth(i, w) }
+	return t
+}
+
+// clearMonitor empties the decoded monitor
+func (s *AppState) clearMonitor() {
+	s.rows = nil
+	s.monitor.Refresh()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatStamp(t *testing.T) {
+	ref := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	at := ref.Add(1125 * time.Millisecond)
+	for _, tc := range []struct {
+		mode string
+		ref  time.Time
+		want string
+	}{
+		{"clock", ref, "03:04:06.125"},
+		{"since connect", ref, "+1.125s"},
+		{"since first message", ref, "+1.125s"},
+		{"since connect", time.Time{}, "03:04:06.125"}, // nothing to count from yet
+	} {
+		if got := formatStamp(tc.mode, at, tc.ref); got != tc.want { t.Errorf("formatStamp(%q) = %q, want %q", tc.mode, got, tc.want) }
+	}
+}
+
+func TestStamp(t *testing.T) {
+	s := newAppState()
+	conn := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	s.connectedAt, s.firstMsgAt = conn, conn.Add(time.Second)
+	at := conn.Add(2500 * time.Millisecond)
+	for mode, want := range map[string]string{"clock": "03:04:07.500", "since connect": "+2.500s", "since first message": "+1.500s"} {
+		s.stampMode.SetSelected(mode)
+		if got := s.stamp(at); got != want { t.Errorf("%s: stamp = %q, want %q", mode, got, want) }
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// voice is the per-note expression state of one MPE member channel
+type voice struct {
+	note, vel             uint8
+	held                  bool
+	bend, pressure, slide float64
+}
+
+// addTo adds the voice's expression to the note variables
+func (v voice) addTo(vs vars) vars {
+	vs["bend"], vs["pressure"], vs["slide"] = v.bend, v.pressure, v.slide
+	return vs
+}
+
+// parseMembers validates the MPE member channel count
+func parseMembers(text string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || n < 1 || n > 15 { return 0, fmt.Errorf("member count must be 1-15") }
+	return n, nil
+}
+
+// mpeMember reports whether ch is a member channel of the configured zone
+func (s *AppState) mpeMember(ch uint8) bool {
+	n, err := parseMembers(s.mpeMembers.Text)
+	if err != nil { n = 15 }
+	if strings.HasPrefix(s.mpeMaster.Selected, "upper") {
+		return ch < 15 && int(ch) >= 15-n
+	}
+	return ch > 0 && int(ch) <= n
+}
+
+// mpeTransform handles messages on MPE member channels, tracking each channel's voice.
+// Messages outside the zone are left for the regular templates.
+func (s *AppState) mpeTransform(msg midi.Message, merged bool, dur time.Duration) (string, bool) {
+	var ch, key, vel, cc, val, pressure uint8
+	var bend int16
+	var abs uint16
+	if !msg.GetChannel(&ch) { return "", false }
+	if !s.mpeMember(ch) { return "", false }
+	s.mu.Lock()
+	v := &s.voices[ch]
+	var name, tpl string
+	var vs vars
+	switch {
+	case msg.GetNoteStart(&ch, &key, &vel):
+		v.note, v.vel, v.held = key, vel, true
+		name, tpl, vs = "note-on", s.noteOnTpl.Text, v.addTo(s.noteVars(ch, key, vel))
+	case msg.GetNoteOff(&ch, &key, &vel), msg.GetNoteEnd(&ch, &key):
+		v.held = false
+		name, tpl, vel = s.releaseTemplate(vel, merged)
+		vs = v.addTo(s.releaseVars(ch, key, vel, dur))
+	case msg.GetPitchBend(&ch, &bend, &abs): v.bend = float64(bend) / 8192
+	case msg.GetAfterTouch(&ch, &pressure): v.pressure = float64(pressure) / 127
+	case msg.GetControlChange(&ch, &cc, &val) && cc == 74: v.slide = float64(val) / 127
+	default:
+		s.mu.Unlock()
+		return "", false
+	}
+	if name == "" && v.held { name, tpl, vs = "mpe-expr", s.mpeTpl.Text, v.addTo(s.noteVars(ch, v.note, v.vel)) }
+	s.mu.Unlock()
+	if vs == nil { return "", true }
+	return s.render(name, tpl, vs), true
+}
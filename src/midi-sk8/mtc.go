@@ -0,0 +1,48 @@
+package main
+
+import "fmt"
+
+// mtcRates maps the two frame-rate bits of quarter-frame piece 7 to frames per second
+var mtcRates = [4]float64{24, 25, 29.97, 30}
+
+// mtcState collects the eight MTC quarter-frame pieces into a full timecode
+type mtcState struct {
+	pieces [8]uint8
+	seen   uint8
+	last   vars
+}
+
+// add stores one quarter-frame and reports whether it completed a timecode.
+// A frame completes on piece 7 once all eight pieces have arrived.
+func (m *mtcState) add(qf uint8) bool {
+	piece := qf >> 4 & 7
+	m.pieces[piece], m.seen = qf&0x0F, m.seen|1<<piece
+	if piece != 7 || m.seen != 0xFF { return false }
+	p := m.pieces
+	ff, ss, mm := p[0]|(p[1]&1)<<4, p[2]|(p[3]&3)<<4, p[4]|(p[5]&3)<<4
+	hh, rate := p[6]|(p[7]&1)<<4, p[7]>>1&3
+	m.last = vars{
+		"tc": fmt.Sprintf("%02d:%02d:%02d:%02d", hh, mm, ss, ff),
+		"hh": float64(hh), "mm": float64(mm), "ss": float64(ss), "ff": float64(ff), "fps": mtcRates[rate],
+	}
+	m.seen = 0
+	return true
+}
+
+// vars returns the latest complete timecode plus the quarter-frame piece number
+func (m *mtcState) vars(qf uint8) vars {
+	vs := vars{"qf": float64(qf >> 4 & 7)}
+	for k, v := range m.last { vs[k] = v }
+	return vs
+}
+
+// mtcTransform assembles quarter-frames and renders the mtc template either per
+// complete frame or, once a first frame is known, on every quarter-frame
+func (s *AppState) mtcTransform(qf uint8) string {
+	s.mu.Lock()
+	complete := s.mtc.add(qf)
+	known, vs := s.mtc.last != nil, s.mtc.vars(qf)
+	s.mu.Unlock()
+	if !known || !complete && s.mtcMode.Selected != "every quarter-frame" { return "" }
+	return s.render("mtc", s.mtcTpl.Text, vs)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// noteKey identifies a sounding note by raw channel and key
+type noteKey struct{ ch, key uint8 }
+
+// heldNote is how and when a sounding note started
+type heldNote struct {
+	merged bool
+	at     time.Time
+}
+
+// trackHeld records note starts and ends so they can be released on teardown, and
+// lights them on the keyboard. Each note remembers whether note-offs were merged when
+// it started, and a note end returns that, so a note is released the way it began even
+// when the setting is flipped while it sounds. A note end also returns how long the
+// note was held; a retriggered note counts from its latest start and a note end with
+// no start has a duration of 0.
+func (s *AppState) trackHeld(msg midi.Message, now time.Time) (merged bool, dur time.Duration) {
+	var ch, key, vel uint8
+	merged = s.mergeOff.Checked
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case msg.GetNoteStart(&ch, &key, &vel):
+		if s.held == nil { s.held = map[noteKey]heldNote{} }
+		s.held[noteKey{ch, key}] = heldNote{merged, now}
+		if s.lastNote == nil { s.lastNote = map[uint8]uint8{} }
+		s.lastNote[ch] = key
+		s.piano.note(ch, key, true)
+	case msg.GetNoteEnd(&ch, &key):
+		if h, ok := s.held[noteKey{ch, key}]; ok { merged, dur = h.merged, max(now.Sub(h.at), 0) }
+		delete(s.held, noteKey{ch, key})
+		s.piano.note(ch, key, false)
+	}
+	return merged, dur
+}
+
+// latchNote turns notes into toggles while latch is on: a press on an idle key starts
+// the note, the next press on that key and channel ends it, and real note ends are
+// ignored, reported by the second result
+func (s *AppState) latchNote(msg midi.Message) (midi.Message, bool) {
+	if !s.latch.Checked { return msg, false }
+	var ch, key, vel uint8
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case msg.GetNoteStart(&ch, &key, &vel):
+		k := noteKey{ch, key}
+		if s.latched[k] { delete(s.latched, k); return midi.NoteOffVelocity(ch, key, vel), false }
+		if s.latched == nil { s.latched = map[noteKey]bool{} }
+		s.latched[k] = true
+	case msg.GetNoteEnd(&ch, &key): return msg, true
+	}
+	return msg, false
+}
+
+// releaseTemplate picks how a note end is sent: the note-off template, or with
+// merged note-offs the note-on template at velocity 0
+func (s *AppState) releaseTemplate(vel uint8, merged bool) (name, tpl string, v uint8) {
+	if merged { return "note-on", s.noteOnTpl.Text, 0 }
+	return "note-off", s.noteOffTpl.Text, vel
+}
+
+// toggleMerge flips between separate and merged note-offs while connected
+func (s *AppState) toggleMerge() {
+	s.mergeOff.SetChecked(!s.mergeOff.Checked)
+	mode := "separate note-off"
+	if s.mergeOff.Checked { mode = "note-on v0" }
+	s.appendLog(s.udpLog, "! note ends now sent as "+mode+" (held notes keep their mode)")
+}
+
+// releaseHeld sends the note-off template for every note still held, so downstream
+// voices do not drone after the listener goes away
+func (s *AppState) releaseHeld() {
+	s.mu.Lock()
+	held := s.held
+	keys := make([]noteKey, 0, len(held))
+	for k := range held { keys = append(keys, k) }
+	s.held, s.lastNote, s.shifted, s.latched = nil, nil, nil, nil
+	s.voices = [16]voice{}
+	s.mu.Unlock()
+	s.piano.clear()
+	now := s.clock()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ch < keys[j].ch || keys[i].ch == keys[j].ch && keys[i].key < keys[j].key })
+	for _, k := range keys {
+		name, tpl, v := s.releaseTemplate(0, held[k].merged)
+		out := s.render(name, tpl, s.releaseVars(k.ch, k.key, v, now.Sub(held[k].at)))
+		if out == "" || s.sendMsg(out, midi.NoteOff(k.ch, k.key)) != nil { continue }
+		s.appendLog(s.udpLog, out+" (release)")
+	}
+}
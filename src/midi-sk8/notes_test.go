@@ -0,0 +1,134 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestReleaseHeld(t *testing.T) {
+	s := newAppState()
+	out := testOutput(s)
+	s.noteOffTpl.SetText("off c$c n$n")
+	now := time.Now()
+	for _, msg := range []midi.Message{midi.NoteOn(1, 64, 100), midi.NoteOn(0, 62, 100), midi.NoteOn(0, 60, 100), midi.NoteOff(0, 62)} { s.trackHeld(msg, now) }
+	s.releaseHeld()
+	if got, want := out.got(), []string{"off c1 n60", "off c2 n64"}; !slices.Equal(got, want) { t.Errorf("released %q, want %q", got, want) }
+	if s.held != nil { t.Errorf("still held: %v", s.held) }
+	s.releaseHeld()
+	if got := out.got(); len(got) != 2 { t.Errorf("a second release sent %q again", got[2:]) }
+}
+
+// with merged note-offs a note end goes out through the note-on template at velocity 0
+func TestMergedNoteOff(t *testing.T) {
+	s := newAppState()
+	s.noteOnTpl.SetText("on n$n v$v")
+	s.noteOffTpl.SetText("off n$n v$v")
+	for _, tc := range []struct {
+		merged bool
+		end    midi.Message
+		want   string
+	}{
+		{false, midi.NoteOffVelocity(0, 60, 40), "off n60 v40"},
+		{false, midi.NoteOn(0, 60, 0), "off n60 v0"},
+		{true, midi.NoteOffVelocity(0, 60, 40), "on n60 v0"},
+		{true, midi.NoteOn(0, 60, 0), "on n60 v0"},
+	} {
+		s.mergeOff.SetChecked(tc.merged)
+		s.dispatch(midi.NoteOn(0, 60, 100), time.Now())
+		if got, _, _ := s.dispatch(tc.end, time.Now()); got != tc.want { t.Errorf("merged %v: %v sent %q, want %q", tc.merged, tc.end, got, tc.want) }
+	}
+	if name, tpl, v := s.releaseTemplate(99, true); name != "note-on" || tpl != "on n$n v$v" || v != 0 { t.Errorf("releaseTemplate(merged) = %q, %q, %d", name, tpl, v) }
+}
+
+// flipping the mode while notes sound releases each the way it started, both on its own
+// note end and on teardown
+func TestToggleMergeHeld(t *testing.T) {
+	s := newAppState()
+	out := testOutput(s)
+	s.noteOnTpl.SetText("on n$n v$v")
+	s.noteOffTpl.SetText("off n$n v$v")
+	send := func(msg midi.Message) { if p, m, _ := s.dispatch(msg, time.Now()); p != "" { s.sendMsg(p, m) } }
+	send(midi.NoteOn(0, 60, 100)) // separate
+	send(midi.NoteOn(0, 61, 100))
+	s.toggleMerge()
+	send(midi.NoteOn(0, 62, 100)) // merged
+	send(midi.NoteOff(0, 60))
+	send(midi.NoteOff(0, 62))
+	send(midi.NoteOn(0, 63, 100))
+	s.toggleMerge()
+	send(midi.NoteOff(0, 63))
+	s.releaseHeld()
+	want := []string{"on n60 v100", "on n61 v100", "on n62 v100", "off n60 v0", "on n62 v0", "on n63 v100", "on n63 v0", "off n61 v0"}
+	if got := out.got(); !slices.Equal(got, want) { t.Errorf("sent %q, want %q", got, want) }
+	if got := s.logRingFor(s.udpLog).text(); !strings.Contains(got, "now sent as note-on v0") || !strings.Contains(got, "now sent as separate note-off") { t.Errorf("log = %q", got) }
+}
+
+func TestNoteDuration(t *testing.T) {
+	s := newAppState()
+	s.noteOnTpl.SetText("")
+	s.noteOffTpl.SetText("off n$n d$dur")
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		msg  midi.Message
+		at   time.Duration
+		want string
+	}{
+		{midi.NoteOn(0, 60, 100), 0, ""},
+		{midi.NoteOff(0, 60), 250 * time.Millisecond, "off n60 d250"},
+		{midi.NoteOff(0, 61), time.Second, "off n61 d0"}, // no note-on
+		{midi.NoteOff(0, 60), time.Second, "off n60 d0"}, // already released
+		{midi.NoteOn(1, 60, 100), 0, ""},
+		{midi.NoteOn(1, 60, 100), 100 * time.Millisecond, ""}, // a retrigger counts from here
+		{midi.NoteOn(1, 60, 0), 400 * time.Millisecond, "off n60 d300"},
+	} {
+		if got, _, _ := s.dispatch(tc.msg, t0.Add(tc.at)); got != tc.want { t.Errorf("%v at %v sent %q, want %q", tc.msg, tc.at, got, tc.want) }
+	}
+	if len(s.held) != 0 { t.Errorf("still held after release: %v", s.held) }
+}
+
+// with latch on, presses toggle each key per channel and real note-offs are ignored
+func TestLatch(t *testing.T) {
+	s := newAppState()
+	s.noteOnTpl.SetText("on c$c n$n")
+	s.noteOffTpl.SetText("off c$c n$n")
+	s.latch.SetChecked(true)
+	for i, tc := range []struct {
+		msg  midi.Message
+		want string
+	}{
+		{midi.NoteOn(0, 60, 100), "on c1 n60"},
+		{midi.NoteOff(0, 60), ""},
+		{midi.NoteOn(1, 60, 100), "on c2 n60"}, // another channel is another key
+		{midi.NoteOn(0, 62, 100), "on c1 n62"},
+		{midi.NoteOn(0, 60, 100), "off c1 n60"},
+		{midi.NoteOn(0, 60, 0), ""}, // a release too, so ignored
+		{midi.NoteOn(0, 60, 100), "on c1 n60"},
+		{midi.NoteOn(1, 60, 100), "off c2 n60"},
+	} {
+		if got, _, _ := s.dispatch(tc.msg, time.Time{}); got != tc.want { t.Errorf("press %d, %v: sent %q, want %q", i, tc.msg, got, tc.want) }
+	}
+	if _, ok := s.held[noteKey{0, 62}]; len(s.held) != 2 || !ok { t.Errorf("held %v, want ch0 keys 60 and 62", s.held) }
+	// releasing stops the latched notes and starts the next latch afresh
+	out := testOutput(s)
+	s.releaseHeld()
+	if got, want := out.got(), []string{"off c1 n60", "off c1 n62"}; !slices.Equal(got, want) { t.Errorf("released %q, want %q", got, want) }
+	if got, _, _ := s.dispatch(midi.NoteOn(0, 60, 100), time.Time{}); got != "on c1 n60" { t.Errorf("press after release sent %q", got) }
+	s.latch.SetChecked(false)
+	if got, _, _ := s.dispatch(midi.NoteOff(0, 60), time.Time{}); got != "off c1 n60" { t.Errorf("note-off with latch off sent %q", got) }
+}
+
+// released on teardown, a note's $dur runs to the injected clock like a real note-off's
+func TestReleaseHeldDuration(t *testing.T) {
+	s := newAppState()
+	out := testOutput(s)
+	s.noteOffTpl.SetText("off n$n d$dur")
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.clock = func() time.Time { return t0.Add(750 * time.Millisecond) }
+	s.dispatch(midi.NoteOn(0, 60, 100), t0)
+	s.releaseHeld()
+	if got := out.got(); !slices.Equal(got, []string{"off n60 d750"}) { t.Errorf("released %q, want [off n60 d750]", got) }
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver"
+)
+
+// errNoPin is returned where the window cannot be kept on top
+var errNoPin = errors.New("always on top is not supported by this platform")
+
+// canPin reports whether the window can be kept above others: natively on Windows,
+// through wmctrl on X11. Fyne has no API for it, so elsewhere the option is not offered.
+func canPin() bool {
+	switch runtime.GOOS {
+	case "windows": return true
+	case "darwin", "android", "ios": return false
+	}
+	_, err := exec.LookPath("wmctrl")
+	return err == nil && os.Getenv("DISPLAY") != ""
+}
+
+// pinWindow keeps w above other windows, or lets it go back to normal stacking.
+// Dialogs are drawn inside the window, so they stay on top with it.
+func pinWindow(w fyne.Window, on bool) error {
+	nw, ok := w.(driver.NativeWindow)
+	if !ok { return errNoPin }
+	err := errNoPin
+	nw.RunNative(func(ctx any) {
+		switch c := ctx.(type) {
+		case driver.WindowsWindowContext: err = setTopmost(c.HWND, on)
+		case driver.X11WindowContext:
+			op := "remove,above"
+			if on { op = "add,above" }
+			err = exec.Command("wmctrl", "-i", "-r", fmt.Sprintf("0x%x", c.WindowHandle), "-b", op).Run()
+		}
+	})
+	return err
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// setTopmost is only needed on Windows; X11 goes through wmctrl
+func setTopmost(uintptr, bool) error { return errNoPin }
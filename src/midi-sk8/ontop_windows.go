@@ -0,0 +1,16 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+var setWindowPos = syscall.NewLazyDLL("user32.dll").NewProc("SetWindowPos")
+
+// setTopmost moves a window into or out of the topmost band, leaving its size and position
+func setTopmost(hwnd uintptr, on bool) error {
+	const swpNoSize, swpNoMove = 0x1, 0x2
+	after := ^uintptr(1) // HWND_NOTOPMOST
+	if on { after = ^uintptr(0) } // HWND_TOPMOST
+	if ok, _, err := setWindowPos.Call(hwnd, after, 0, 0, 0, 0, swpNoSize|swpNoMove); ok == 0 { return err }
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// oversizeModes are what happens to a payload too big for one UDP datagram
+var oversizeModes = []string{"drop", "split", "tcp"}
+
+// defaultMaxDatagram stays under a typical 1500-byte MTU once IP and UDP headers are added
+const defaultMaxDatagram = 1400
+
+// errOversize is returned when an oversized payload is dropped
+var errOversize = errors.New("payload too big for one datagram")
+
+// maxDatagram reads the datagram size limit, defaulting to 1400 bytes
+func (s *AppState) maxDatagram() int {
+	if n, err := parseNonNegative("max datagram", s.maxUDP.Text); err == nil && n > 0 { return n }
+	return defaultMaxDatagram
+}
+
+// datagrams reports whether the output sends UDP datagrams, where payload size matters
+func (s *AppState) datagrams() bool {
+	return s.router != nil || (s.udpConn != nil && s.outMode.Selected == "udp")
+}
+
+// fragments cuts data into pieces of at most n bytes
+func fragments(data []byte, n int) [][]byte {
+	var out [][]byte
+	for len(data) > n { out = append(out, data[:n]); data = data[n:] }
+	return append(out, data)
+}
+
+// oversize deals with a framed payload for sink k over the limit as the on-oversize
+// setting says, logging what it did. Split pieces and TCP sends bypass batching.
+func (s *AppState) oversize(k *sink, data []byte, dest string, limit int) error {
+	addr, on := dest, ""
+	datagram := func(p []byte) error { return k.write(p, s.dialTimeout()) }
+	if k.main {
+		if addr == "" && s.router != nil { addr = s.router.def }
+		datagram = func(p []byte) error {
+			var err error
+			if addr != "" && s.router != nil { _, err = s.router.WriteTo(p, addr) } else { _, err = s.udpConn.Write(p) }
+			return err
+		}
+	} else {
+		addr, on = k.conf.dest, " for output "+k.conf.String()
+	}
+	switch s.onOversize.Selected {
+	case "split":
+		parts := fragments(data, limit)
+		for _, p := range parts {
+			if err := datagram(p); err != nil { return err }
+		}
+		s.appendLog(s.udpLog, fmt.Sprintf("! split %d-byte payload into %d datagrams of up to %d bytes%s", len(data), len(parts), limit, on))
+		return nil
+	case "tcp":
+		if addr == "" { addr = s.udpConn.RemoteAddr().String() }
+		if err := s.writeTCP(addr, data); err != nil { return fmt.Errorf("sending %d-byte payload over tcp to %s: %w", len(data), addr, err) }
+		s.appendLog(s.udpLog, fmt.Sprintf("! sent %d-byte payload over tcp to %s", len(data), addr))
+		return nil
+	}
+	s.appendLog(s.udpLog, fmt.Sprintf("! dropped %d-byte payload, over the %d-byte datagram limit%s", len(data), limit, on))
+	return errOversize
+}
+
+// writeTCP sends to addr over a TCP connection kept for the session, redialling after a failure
+func (s *AppState) writeTCP(addr string, data []byte) error {
+	s.mu.Lock()
+	c := s.tcpConns[addr]
+	s.mu.Unlock()
+	if c == nil {
+		var err error
+		if c, err = net.DialTimeout("tcp", addr, s.dialTimeout()); err != nil { return err }
+		s.mu.Lock()
+		if s.tcpConns == nil { s.tcpConns = map[string]net.Conn{} }
+		s.tcpConns[addr] = c
+		s.mu.Unlock()
+	}
+	if _, err := c.Write(data); err != nil {
+		c.Close()
+		s.mu.Lock(); delete(s.tcpConns, addr); s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// closeTCP closes the fallback connections of the ended session
+func (s *AppState) closeTCP() {
+	s.mu.Lock()
+	conns := s.tcpConns
+	s.tcpConns = nil
+	s.mu.Unlock()
+	for _, c := range conns { c.Close() }
+}
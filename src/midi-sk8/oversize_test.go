@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFragments(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		n    int
+		want []string
+	}{
+		{"abcdefg", 3, []string{"abc", "def", "g"}},
+		{"abcdef", 3, []string{"abc", "def"}},
+		{"ab", 3, []string{"ab"}},
+	} {
+		var got []string
+		for _, p := range fragments([]byte(tc.data), tc.n) { got = append(got, string(p)) }
+		if !slices.Equal(got, tc.want) { t.Errorf("fragments(%q, %d) = %q, want %q", tc.data, tc.n, got, tc.want) }
+	}
+}
+
+// udpOutput points s at a UDP listener with an 8-byte datagram limit; small payloads
+// still go through the recorder
+func udpOutput(t *testing.T, s *AppState, pc net.PacketConn) *recorder {
+	t.Helper()
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil { t.Fatal(err) }
+	t.Cleanup(func() { conn.Close(); s.closeTCP() })
+	r := testOutput(s)
+	s.udpConn = conn
+	s.outMode.SetSelected("udp")
+	s.maxUDP.SetText("8")
+	return r
+}
+
+func TestOversize(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		s := newAppState()
+		r := udpOutput(t, s, listenUDP(t))
+		s.onOversize.SetSelected("drop")
+		if err := s.send("0123456789"); err != errOversize { t.Errorf("send = %v, want %v", err, errOversize) }
+		if err := s.send("small"); err != nil { t.Errorf("send of a small payload: %v", err) }
+		if got := r.got(); !slices.Equal(got, []string{"small"}) { t.Errorf("sent %q, want [small]", got) }
+		if got := s.logRingFor(s.udpLog).text(); !strings.Contains(got, "dropped 10-byte payload") { t.Errorf("log = %q", got) }
+	})
+	t.Run("split", func(t *testing.T) {
+		s := newAppState()
+		pc := listenUDP(t)
+		udpOutput(t, s, pc)
+		s.onOversize.SetSelected("split")
+		if err := s.send("0123456789"); err != nil { t.Fatalf("send: %v", err) }
+		if a, b := readUDP(t, pc), readUDP(t, pc); a != "01234567" || b != "89" { t.Errorf("datagrams %q %q, want 01234567 89", a, b) }
+		if got := s.logRingFor(s.udpLog).text(); !strings.Contains(got, "into 2 datagrams") { t.Errorf("log = %q", got) }
+	})
+	t.Run("tcp", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil { t.Fatal(err) }
+		defer ln.Close()
+		pc, err := net.ListenPacket("udp", ln.Addr().String())
+		if err != nil { t.Skipf("no UDP port matching the TCP listener: %v", err) }
+		defer pc.Close()
+		s := newAppState()
+		udpOutput(t, s, pc)
+		s.onOversize.SetSelected("tcp")
+		got := make(chan string, 1)
+		go func() {
+			c, err := ln.Accept()
+			if err != nil { got <- err.Error(); return }
+			defer c.Close()
+			b, _ := io.ReadAll(io.LimitReader(c, 10))
+			got <- string(b)
+		}()
+		if err := s.send("0123456789"); err != nil { t.Fatalf("send: %v", err) }
+		if g := <-got; g != "0123456789" { t.Errorf("tcp got %q", g) }
+		if got := s.logRingFor(s.udpLog).text(); !strings.Contains(got, "over tcp to "+ln.Addr().String()) { t.Errorf("log = %q", got) }
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// baseAppID is the original app ID, kept so the default profile reuses existing preferences
+const baseAppID = "com.sk8r.midi-udp"
+
+var profileRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// appID suffixes the base ID with the profile so each instance has its own preferences
+func appID(profile string) (string, error) {
+	if profile == "" { return baseAppID, nil }
+	if !profileRegex.MatchString(profile) { return "", fmt.Errorf("profile %q may only use letters, digits, - and _", profile) }
+	return baseAppID + "." + profile, nil
+}
+
+// prefEntries lists the entries remembered between runs, keyed by preference name
+func (s *AppState) prefEntries() map[string]*widget.Entry {
+	m := map[string]*widget.Entry{
+		"udp-addr": s.addrEntry, "udp-port": s.portEntry, "type-ports": s.typePorts, "dial-timeout": s.dialMs, "socket-path": s.sockPath, "exec-command": s.execCmd, "echo-port": s.echoPort, "color-in": s.colorIn, "color-out": s.colorOut, "serial-port": &s.serialPort.Entry, "serial-baud": s.serialBaud,
+		"batch-size": s.batchSize, "batch-ms": s.batchMs, "max-datagram": s.maxUDP, "rate-limit": s.rateLimit, "rate-burst": s.rateBurst, "delay-ms": s.delayMs, "delay-jitter": s.jitterMs, "deadband": s.deadband, "glide-ms": s.glideMs, "glide-hz": s.glideHz, "virtual-name": s.virtualName,
+		"midi-retry": s.retryCount, "midi-retry-ms": s.retryMs, "midi-buffer": s.inBuf, "cc-map": s.ccMapEntry, "invert": s.invertEntry, "chan-adjust": s.adjEntry, "macros": s.macroEntry, "clamp": s.clampEntry, "watch-path": s.watchPath, "script": s.scriptEntry, "script-ms": s.scriptMs, "log-lines": s.logLines, "ui-interval": s.repaintMs, "trigger": s.trigEntry,
+		"mpe-members": s.mpeMembers, "bend-range": s.bendRange,
+	}
+	for k, e := range s.templates() { m["tpl."+k] = e }
+	return m
+}
+
+// prefSelects lists the remembered drop-downs
+func (s *AppState) prefSelects() map[string]*widget.Select {
+	return map[string]*widget.Select{
+		"output": s.outMode, "framing": s.framing, "encoding": s.encoding, "on-error": s.onError, "channels": s.chanNumbers, "mpe-master": s.mpeMaster, "mtc-mode": s.mtcMode, "curve": s.velCurve, "release-curve": s.relCurve, "midi-driver": s.driverSel, "midi-out": s.midiOutSel, "timestamps": s.stampMode, "bytes": s.byteMode, "rate-mode": s.rateMode, "oversize": s.onOversize, "lfo-rate": s.lfoRate, "lfo-shape": s.lfoShape, "theme": s.themeMode,
+	}
+}
+
+// loadPrefs overrides the defaults with whatever this profile saved last time
+func (s *AppState) loadPrefs(p fyne.Preferences) {
+	// selects first, so entries naming channels are read in the saved channel numbering
+	for k, sel := range s.prefSelects() { sel.SetSelected(p.StringWithFallback(k, sel.Selected)) }
+	for k, e := range s.prefEntries() { e.SetText(p.StringWithFallback(k, e.Text)) }
+	s.wantIn = p.StringWithFallback("midi-in", s.wantIn)
+	s.mpeCheck.SetChecked(p.BoolWithFallback("mpe", s.mpeCheck.Checked))
+	s.curveAt.SetChecked(p.BoolWithFallback("curve-pressure", s.curveAt.Checked))
+	s.virtualIn.SetChecked(p.BoolWithFallback("virtual-in", s.virtualIn.Checked))
+	s.mergeOff.SetChecked(p.BoolWithFallback("note-off-as-on", s.mergeOff.Checked))
+	s.bendNote.SetChecked(p.BoolWithFallback("bend-as-note", s.bendNote.Checked))
+	s.latch.SetChecked(p.Bool("latch"))
+	s.collapse.SetChecked(p.BoolWithFallback("collapse-log", s.collapse.Checked))
+	s.minimal = p.Bool("minimal")
+	s.seqTags.SetChecked(p.Bool("seq-tags"))
+	s.lfoUni.SetChecked(p.Bool("lfo-unipolar"))
+	s.onTop.SetChecked(p.Bool("always-on-top"))
+	s.showKeys = p.Bool("keyboard")
+	s.bpm = p.FloatWithFallback("bpm", s.bpm)
+	s.strictVars.SetChecked(p.BoolWithFallback("strict-vars", s.strictVars.Checked))
+	s.clearOnConn.SetChecked(p.BoolWithFallback("clear-on-connect", s.clearOnConn.Checked))
+	s.autoPause.SetChecked(p.BoolWithFallback("pause-on-trigger", s.autoPause.Checked))
+	s.execEach.SetChecked(p.BoolWithFallback("exec-per-message", s.execEach.Checked))
+	s.logGroup.SetSelected(p.StringListWithFallback("log-kinds", s.logGroup.Selected))
+	s.sendGroup.SetSelected(p.StringListWithFallback("send-kinds", s.sendGroup.Selected))
+	for _, key := range p.StringList("tpl-off") { if c, ok := s.tplOn[key]; ok { c.SetChecked(false) } }
+}
+
+// savePrefs stores the current settings for this profile
+func (s *AppState) savePrefs(p fyne.Preferences) {
+	for k, e := range s.prefEntries() { p.SetString(k, e.Text) }
+	for k, sel := range s.prefSelects() { p.SetString(k, sel.Selected) }
+	p.SetString("midi-in", s.wantIn)
+	p.SetBool("mpe", s.mpeCheck.Checked)
+	p.SetBool("curve-pressure", s.curveAt.Checked)
+	p.SetBool("virtual-in", s.virtualIn.Checked)
+	p.SetBool("note-off-as-on", s.mergeOff.Checked)
+	p.SetBool("bend-as-note", s.bendNote.Checked)
+	p.SetBool("latch", s.latch.Checked)
+	p.SetBool("collapse-log", s.collapse.Checked)
+	p.SetBool("minimal", s.minimal)
+	p.SetBool("seq-tags", s.seqTags.Checked)
+	p.SetBool("lfo-unipolar", s.lfoUni.Checked)
+	p.SetBool("always-on-top", s.onTop.Checked)
+	p.SetBool("keyboard", s.showKeys)
+	s.mu.Lock(); p.SetFloat("bpm", s.bpm); s.mu.Unlock()
+	p.SetBool("strict-vars", s.strictVars.Checked)
+	p.SetBool("clear-on-connect", s.clearOnConn.Checked)
+	p.SetBool("pause-on-trigger", s.autoPause.Checked)
+	p.SetBool("exec-per-message", s.execEach.Checked)
+	p.SetStringList("log-kinds", s.logGroup.Selected)
+	p.SetStringList("send-kinds", s.sendGroup.Selected)
+	p.SetStringList("tpl-off", s.disabledTpls())
+	p.SetStringList("sinks", s.sinkPrefs())
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"midi-sk8/internal/tpl"
+)
+
+// presetVersion is bumped whenever the .sk8 layout changes incompatibly
+const presetVersion = 1
+
+// Preset is the shareable mapping written to and read from .sk8 files
+type Preset struct {
+	Version   int               `json:"version"`
+	App       string            `json:"app,omitempty"`
+	Templates map[string]string `json:"templates"`
+	CCMap     map[uint8]string  `json:"cc_map,omitempty"`
+	Invert    string            `json:"invert,omitempty"`
+	Macros    string            `json:"macros,omitempty"`
+	Adjust    string            `json:"chan_adjust,omitempty"`
+}
+
+// templateKeys are the template names a preset may carry
+var templateKeys = []string{"note-on", "note-off", "pitch-bend", "cc", "chan-mode", "aftertouch", "poly-at", "mpe-expr", "mtc"}
+
+// templates maps each preset key to the entry holding that template
+func (s *AppState) templates() map[string]*widget.Entry {
+	return map[string]*widget.Entry{
+		"note-on": s.noteOnTpl, "note-off": s.noteOffTpl, "pitch-bend": s.pbTpl, "cc": s.ccTpl, "chan-mode": s.modeTpl, "aftertouch": s.atTpl, "poly-at": s.polyAtTpl, "mpe-expr": s.mpeTpl, "mtc": s.mtcTpl,
+	}
+}
+
+// snapshot captures the current mapping as a Preset
+func (s *AppState) snapshot() Preset {
+	p := Preset{Version: presetVersion, App: "midi-sk8 " + version, Templates: map[string]string{}}
+	for k, e := range s.templates() { p.Templates[k] = e.Text }
+	if m, err := parseCCMap(s.ccMapEntry.Text); err == nil && len(m) > 0 { p.CCMap = m }
+	p.Invert = s.invertEntry.Text
+	p.Macros = s.macroEntry.Text
+	// presets number channels 1-16 whatever the display, so they read the same everywhere
+	if m, err := parseChanAdjust(s.adjEntry.Text, s.channelBase()); err == nil && len(m) > 0 { p.Adjust = formatChanAdjust(m, 1) }
+	return p
+}
+
+// parsePreset decodes and validates a preset, returning any non-fatal incompatibilities
+func parsePreset(data []byte) (Preset, []string, error) {
+	var p Preset
+	if err := json.Unmarshal(data, &p); err != nil { return p, nil, fmt.Errorf("not a valid .sk8 preset: %w", err) }
+	if p.Version == 0 { return p, nil, fmt.Errorf("preset has no version field") }
+	if p.Version > presetVersion {
+		return p, nil, fmt.Errorf("preset version %d is newer than this build supports (%d)", p.Version, presetVersion)
+	}
+	var warnings []string
+	for k := range p.Templates {
+		if !slices.Contains(templateKeys, k) { warnings = append(warnings, fmt.Sprintf("unknown template %q ignored", k)) }
+	}
+	for cc := range p.CCMap {
+		if cc > 127 { return p, nil, fmt.Errorf("cc_map: %d is not a controller number (0-127)", cc) }
+	}
+	if _, err := parseInvert(p.Invert); err != nil { return p, nil, fmt.Errorf("invert: %w", err) }
+	if _, err := tpl.ParseMacros(p.Macros); err != nil { return p, nil, fmt.Errorf("macros: %w", err) }
+	if _, err := parseChanAdjust(p.Adjust, 1); err != nil { return p, nil, fmt.Errorf("chan_adjust: %w", err) }
+	sort.Strings(warnings)
+	return p, warnings, nil
+}
+
+// applyPreset loads a validated preset into the UI, leaving missing templates untouched
+func (s *AppState) applyPreset(p Preset) {
+	for k, e := range s.templates() {
+		if t, ok := p.Templates[k]; ok { e.SetText(t) }
+	}
+	if p.CCMap != nil { s.ccMapEntry.SetText(formatCCMap(p.CCMap)) }
+	if p.Invert != "" { s.invertEntry.SetText(p.Invert) }
+	if p.Macros != "" { s.macroEntry.SetText(p.Macros) }
+	if m, err := parseChanAdjust(p.Adjust, 1); err == nil && p.Adjust != "" { s.adjEntry.SetText(formatChanAdjust(m, s.channelBase())) }
+}
+
+// importPreset reads, validates and applies a preset, reporting problems instead of failing hard
+func (s *AppState) importPreset(r io.Reader, name string, w fyne.Window) {
+	data, err := io.ReadAll(r)
+	if err != nil { dialog.ShowError(err, w); return }
+	p, warnings, err := parsePreset(data)
+	if err != nil {
+		s.appendLog(s.midiLog, fmt.Sprintf("Error: preset %s: %v", name, err))
+		dialog.ShowError(fmt.Errorf("%s: %w", name, err), w)
+		return
+	}
+	s.applyPreset(p)
+	msg := fmt.Sprintf("Loaded preset %s\n", name)
+	s.appendLog(s.midiLog, "Loaded preset "+name)
+	for _, wrn := range warnings { msg += "warning: " + wrn + "\n"; s.appendLog(s.midiLog, "warning: "+wrn) }
+	if len(warnings) > 0 { dialog.ShowInformation("Preset imported with warnings", msg, w) }
+}
+
+// showExport asks for a destination and writes the current mapping there
+func (s *AppState) showExport(w fyne.Window) {
+	d := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+		if err != nil { dialog.ShowError(err, w); return }
+		if wc == nil { return }
+		defer wc.Close()
+		data, _ := json.MarshalIndent(s.snapshot(), "", "  ")
+		if _, err := wc.Write(data); err != nil { dialog.ShowError(err, w); return }
+		s.presetURI = wc.URI()
+	}, w)
+	d.SetFileName("mapping.sk8")
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".sk8"}))
+	d.Show()
+}
+
+// showImport asks for a .sk8 file and loads it
+func (s *AppState) showImport(w fyne.Window) {
+	d := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil { dialog.ShowError(err, w); return }
+		if rc == nil { return }
+		defer rc.Close()
+		s.importPreset(rc, rc.URI().Name(), w)
+		s.presetURI = rc.URI()
+	}, w)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".sk8"}))
+	d.Show()
+}
+
+// dropPresets loads .sk8 files dropped onto the window and logs anything else it skips
+func (s *AppState) dropPresets(w fyne.Window) func(fyne.Position, []fyne.URI) {
+	return func(_ fyne.Position, uris []fyne.URI) {
+		for _, u := range uris {
+			if u.Extension() != ".sk8" { s.appendLog(s.midiLog, "Ignored dropped file "+u.Name()+" (not a .sk8 preset)"); continue }
+			rc, err := storage.Reader(u)
+			if err != nil { s.appendLog(s.midiLog, fmt.Sprintf("Error: preset %s: %v", u.Name(), err)); continue }
+			s.importPreset(rc, u.Name(), w)
+			rc.Close()
+			s.presetURI = u
+		}
+	}
+}
+
+// backupPreset copies the preset at u to u.bak, replacing the previous backup
+func backupPreset(u fyne.URI) error {
+	r, err := storage.Reader(u)
+	if err != nil { return err }
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil { return err }
+	bak, err := storage.ParseURI(u.String() + ".bak")
+	if err != nil { return err }
+	wc, err := storage.Writer(bak)
+	if err != nil { return err }
+	defer wc.Close()
+	_, err = wc.Write(data)
+	return err
+}
+
+// savePreset writes the mapping back to the preset last imported or exported, after
+// confirming, and keeps the file it replaces as .bak. With no such preset it asks where
+// to export. Export's own file dialog confirms overwrites but truncates the file before
+// handing it over, so only this path can take a backup.
+func (s *AppState) savePreset(w fyne.Window) {
+	u := s.presetURI
+	if u == nil { s.showExport(w); return }
+	dialog.ShowConfirm("Overwrite preset?", fmt.Sprintf("Save the current mapping over %s?\nThe old file is kept as %s.bak.", u.Name(), u.Name()), func(ok bool) {
+		if !ok { return }
+		if exists, _ := storage.Exists(u); exists {
+			if err := backupPreset(u); err != nil { dialog.ShowError(fmt.Errorf("backing up %s: %w", u.Name(), err), w); return }
+		}
+		wc, err := storage.Writer(u)
+		if err != nil { dialog.ShowError(err, w); return }
+		defer wc.Close()
+		data, _ := json.MarshalIndent(s.snapshot(), "", "  ")
+		if _, err := wc.Write(data); err != nil { dialog.ShowError(err, w); return }
+		s.appendLog(s.midiLog, "Saved preset "+u.Name())
+	}, w)
+}
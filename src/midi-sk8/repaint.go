@@ -0,0 +1,100 @@
+package main
+
+import (
+	"cmp"
+	"image/color"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+)
+
+// flashFor is how long an indicator stays lit after a message
+const flashFor = 100 * time.Millisecond
+
+// dotOff is the colour of an indicator that is not lit
+var dotOff = color.NRGBA{R: 80, G: 80, B: 80, A: 255}
+
+// flashState is an indicator's latest flash and the colour it shows on screen
+type flashState struct {
+	c, shown color.NRGBA
+	at       time.Time
+}
+
+// repaintEvery is the repaint interval; 0 repaints as soon as the UI goroutine is free
+func (s *AppState) repaintEvery() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repaint
+}
+
+// afterFunc starts repaint timers; tests replace it to fire them by hand
+var afterFunc = time.AfterFunc
+
+// schedulePaint queues one repaint after every unless one is queued already; the caller
+// holds s.mu
+func (s *AppState) schedulePaint(every time.Duration) {
+	if s.paintDue { return }
+	s.paintDue = true
+	afterFunc(every, func() { fyne.Do(s.paint) })
+}
+
+// paint shows the logs changed since the last repaint and brings the indicators up to
+// date. While one is still lit another repaint is queued, so the last state is always
+// drawn once input stops. It runs on the UI goroutine.
+func (s *AppState) paint() {
+	for e := range s.dirty { s.showLog(e) }
+	clear(s.dirty)
+	now, lit := s.clock(), false
+	var dots []*canvas.Circle
+	var fills []color.NRGBA
+	s.mu.Lock()
+	s.paintDue = false
+	for dot, f := range s.flashes {
+		want := dotOff
+		if now.Sub(f.at) < flashFor { want, lit = f.c, true }
+		if want == f.shown { continue }
+		f.shown = want
+		s.flashes[dot] = f
+		dots, fills = append(dots, dot), append(fills, want)
+	}
+	if lit { s.schedulePaint(cmp.Or(s.repaint, flashFor)) }
+	s.mu.Unlock()
+	for i, dot := range dots { dot.FillColor = fills[i]; dot.Refresh() }
+}
+
+// flashLater records a flash for the next repaint instead of drawing it, reporting
+// false when repaints are not coalesced
+func (s *AppState) flashLater(dot *canvas.Circle, c color.NRGBA) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.repaint == 0 { return false }
+	if s.flashes == nil { s.flashes = map[*canvas.Circle]flashState{} }
+	f := s.flashes[dot]
+	f.c, f.at = c, s.clock()
+	s.flashes[dot] = f
+	s.schedulePaint(s.repaint)
+	return true
+}
+
+// paintLog redraws a log whose ring changed at the next repaint, so a burst of lines
+// is drawn once even at interval 0; it runs on the UI goroutine
+func (s *AppState) paintLog(e *widget.Entry) {
+	every := s.repaintEvery()
+	if s.dirty == nil { s.dirty = map[*widget.Entry]bool{} }
+	s.dirty[e] = true
+	s.mu.Lock(); s.schedulePaint(every); s.mu.Unlock()
+}
+
+// showLog puts a log's ring on screen, keeping the cursor on the line it was on unless
+// following, after the lines dropped off the top since it was last shown
+func (s *AppState) showLog(e *widget.Entry) {
+	row := e.CursorRow - s.logShift[e]
+	delete(s.logShift, e)
+	e.SetText(s.logRingFor(e).render(e.Text))
+	if s.follow { row = strings.Count(e.Text, "\n") }
+	e.CursorRow, e.CursorColumn = max(row, 0), 0
+	e.Refresh()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTimers replaces the repaint timers with ones the test fires by hand
+func fakeTimers(tb testing.TB) *[]func() {
+	tb.Helper()
+	var due []func()
+	afterFunc = func(_ time.Duration, f func()) *time.Timer { due = append(due, f); return nil }
+	tb.Cleanup(func() { afterFunc = time.AfterFunc })
+	return &due
+}
+
+// fire runs the timers due now, reporting how many there were
+func fire(due *[]func()) int {
+	fs := *due
+	*due = nil
+	for _, f := range fs { f() }
+	return len(fs)
+}
+
+// once input stops, the repaint already queued draws the last line; nothing is left
+// waiting after it
+func TestRepaintDrawsLastLine(t *testing.T) {
+	due := fakeTimers(t)
+	for _, every := range []time.Duration{0, 50 * time.Millisecond} {
+		s := newAppState()
+		s.repaint = every
+		for i := range 100 { s.appendLog(s.udpLog, fmt.Sprintf("line %d", i)) }
+		if len(*due) != 1 { t.Fatalf("every %v: %d repaints queued for a burst, want 1", every, len(*due)) }
+		if strings.Contains(s.udpLog.Text, "line 99") { t.Errorf("every %v: drawn before the repaint", every) }
+		fire(due)
+		if !strings.HasSuffix(s.udpLog.Text, "line 98\nline 99\n") { t.Errorf("every %v: log ends %q, want the last line", every, s.udpLog.Text[max(len(s.udpLog.Text)-30, 0):]) }
+		if n := fire(due); n != 0 { t.Errorf("every %v: %d repaints queued after the last one", every, n) }
+	}
+}
+
+// a coalesced flash stays lit until a repaint after flashFor puts it out, and then
+// stops asking for repaints
+func TestRepaintFlashGoesOut(t *testing.T) {
+	due := fakeTimers(t)
+	s := newAppState()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.clock = func() time.Time { return at }
+	s.repaint = 20 * time.Millisecond
+	lit := color.NRGBA{G: 255, A: 255}
+	s.flash(s.indicator, lit)
+	fire(due)
+	if s.indicator.FillColor != lit { t.Errorf("flash shows %v, want %v", s.indicator.FillColor, lit) }
+	at = at.Add(flashFor)
+	if n := fire(due); n != 1 { t.Fatalf("%d repaints queued while lit, want 1", n) }
+	if s.indicator.FillColor != dotOff { t.Errorf("after flashFor shows %v, want off", s.indicator.FillColor) }
+	if n := fire(due); n != 0 { t.Errorf("%d repaints queued once out", n) }
+}
+
+// a sustained stream into a full log, drawn after every line against once per 20
+func BenchmarkRepaint(b *testing.B) {
+	for _, lines := range []int{1, 20} {
+		b.Run(fmt.Sprintf("every %d lines", lines), func(b *testing.B) {
+			due := fakeTimers(b)
+			s := newAppState()
+			for i := range defaultLogLines { s.appendLog(s.udpLog, fmt.Sprintf("> v1 n%d l0.7874", i)) }
+			fire(due)
+			b.ReportAllocs()
+			for b.Loop() {
+				for i := range 20 {
+					s.appendLog(s.udpLog, fmt.Sprintf("> v1 n%d l0.7874", i))
+					if (i+1)%lines == 0 { fire(due) }
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// rttTimeout is how long a sent payload waits for its echo before it counts as lost
+const rttTimeout = 2 * time.Second
+
+// maxPending bounds the payloads waiting for their echo
+const maxPending = 1024
+
+// seqTag finds the " #N" sequence tag at the end of a payload
+var seqTag = regexp.MustCompile(` #([0-9]+)$`)
+
+type sentPayload struct {
+	key string
+	at  time.Time
+}
+
+// roundTrip correlates payloads sent with datagrams the echo listener receives, by
+// sequence tag or, untagged, by content
+type roundTrip struct {
+	pending                   []sentPayload
+	matched, lost, unexpected int
+	total, worst              time.Duration
+}
+
+// sent records a payload, counting the oldest waiting one lost once maxPending wait
+func (r *roundTrip) sent(key string, at time.Time) {
+	if len(r.pending) >= maxPending { r.pending = r.pending[1:]; r.lost++ }
+	r.pending = append(r.pending, sentPayload{key, at})
+}
+
+// received matches an echo with the first payload sent under the same key and returns
+// the round trip. Payloads that have waited longer than rttTimeout are counted lost.
+func (r *roundTrip) received(key string, at time.Time) (time.Duration, bool) {
+	defer r.expire(at)
+	i := slices.IndexFunc(r.pending, func(p sentPayload) bool { return p.key == key })
+	if i < 0 { r.unexpected++; return 0, false }
+	d := at.Sub(r.pending[i].at)
+	r.pending = slices.Delete(r.pending, i, i+1)
+	r.matched++
+	r.total += d
+	r.worst = max(r.worst, d)
+	return d, true
+}
+
+func (r *roundTrip) expire(now time.Time) {
+	r.pending = slices.DeleteFunc(r.pending, func(p sentPayload) bool {
+		old := now.Sub(p.at) > rttTimeout
+		if old { r.lost++ }
+		return old
+	})
+}
+
+func (r *roundTrip) String() string {
+	avg := time.Duration(0)
+	if r.matched > 0 { avg = r.total / time.Duration(r.matched) }
+	return fmt.Sprintf("round trip: %d matched, avg %s, max %s, %d lost, %d unexpected", r.matched, fmtRTT(avg), fmtRTT(r.worst), r.lost, r.unexpected)
+}
+
+func fmtRTT(d time.Duration) string { return fmt.Sprintf("%.2fms", float64(d.Microseconds())/1000) }
+
+// rttKey is what a framed payload or an echoed datagram is matched on: its sequence
+// tag when tagging, otherwise the bytes themselves
+func (s *AppState) rttKey(data []byte) string {
+	if !s.seqTags.Checked { return string(data) }
+	m := seqTag.FindSubmatch(bytes.TrimRight(data, "\r\n\x00"))
+	if m == nil { return "" }
+	return "#" + string(m[1])
+}
+
+// tagged appends the next sequence tag to a payload when tagging is on
+func (s *AppState) tagged(payload string) string {
+	if !s.seqTags.Checked { return payload }
+	s.mu.Lock()
+	s.seq++
+	n := s.seq
+	s.mu.Unlock()
+	return fmt.Sprintf("%s #%d", payload, n)
+}
+
+// noteSent records a framed payload for round-trip matching while the echo listener runs
+func (s *AppState) noteSent(data []byte) {
+	key := s.rttKey(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.echoing { s.rtt.sent(key, time.Now()) }
+}
+
+// echoed matches a received datagram and describes the result for the echo log
+func (s *AppState) echoed(data []byte, at time.Time) string {
+	key := s.rttKey(data)
+	s.mu.Lock()
+	d, ok := s.rtt.received(key, at)
+	s.mu.Unlock()
+	if !ok { return " (not sent by us, or too late)" }
+	return " (round trip " + fmtRTT(d) + ")"
+}
+
+// rttSummary reports the round-trip figures for the stats window, empty before the
+// echo listener first ran
+func (s *AppState) rttSummary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rtt == nil { return "" }
+	return s.rtt.String()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+
+	"midi-sk8/internal/tpl"
+)
+
+// maxRoutes bounds how many destinations a templated address can fan out to
+const maxRoutes = 64
+
+// router sends payloads to destinations resolved per message from the address
+// template, keeping one UDP connection per resolved address
+type router struct {
+	mu      sync.Mutex
+	conns   map[string]net.Conn
+	timeout time.Duration
+	def     string
+}
+
+func newRouter(def string, timeout time.Duration) *router {
+	return &router{conns: map[string]net.Conn{}, timeout: timeout, def: def}
+}
+
+// WriteTo writes p to addr, dialing and caching the connection on first use
+func (r *router) WriteTo(p []byte, addr string) (int, error) {
+	r.mu.Lock()
+	c, ok := r.conns[addr]
+	if !ok {
+		if len(r.conns) >= maxRoutes { r.mu.Unlock(); return 0, fmt.Errorf("more than %d destinations, not routing to %s", maxRoutes, addr) }
+		var err error
+		if c, err = net.DialTimeout("udp", addr, r.timeout); err != nil { r.mu.Unlock(); return 0, err }
+		r.conns[addr] = c
+	}
+	r.mu.Unlock()
+	return c.Write(p)
+}
+
+// Write sends payloads that belong to no message, such as manual sends, to the default destination
+func (r *router) Write(p []byte) (int, error) { return r.WriteTo(p, r.def) }
+
+func (r *router) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr, c := range r.conns { c.Close(); delete(r.conns, addr) }
+	return nil
+}
+
+// routed reports whether a destination uses template variables
+func routed(dest string) bool { return tpl.HasVars(dest) }
+
+// parsePorts reads comma separated "kind: port" overrides, such as "note-on: 9001, cc: 9002"
+func parsePorts(text string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, f := range strings.Split(text, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" { continue }
+		kind, port, ok := strings.Cut(f, ":")
+		kind, port = strings.TrimSpace(kind), strings.TrimSpace(port)
+		if !ok || !slices.Contains(messageKinds, kind) { return nil, fmt.Errorf("%q: expected \"kind: port\" with a kind of %s", f, strings.Join(messageKinds, ", ")) }
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 { return nil, fmt.Errorf("%q: %q is not a port (1-65535)", f, port) }
+		m[kind] = port
+	}
+	return m, nil
+}
+
+// destination resolves the address template with the message's channel and data bytes,
+// so "10.0.0.$c:9000" or "host:{9000+$c}" routes by channel, then swaps in the port
+// override for the message's kind, if any
+func (s *AppState) destination(msg midi.Message) string {
+	var ch, n, v uint8
+	msg.GetChannel(&ch)
+	if b := msg.Bytes(); len(b) > 2 { n, v = b[1], b[2] } else if len(b) > 1 { n = b[1] }
+	dest := s.resolveDest(msgVars(ch, n, v, 0))
+	if port, ok := s.ports[messageKind(msg)]; ok {
+		if host, _, err := net.SplitHostPort(dest); err == nil { dest = net.JoinHostPort(host, port) }
+	}
+	return dest
+}
+
+// resolveDest fills in the address template; {} results are whole numbers so they can
+// be ports or address octets, and the output clamp, meant for payload values, is skipped
+func (s *AppState) resolveDest(vs vars) string {
+	vs["c"] = vs["c"].(float64) + float64(s.channelBase())
+	dest, _ := tpl.TransformWhole(s.routeTpl, vs, s.macroMap(), nil)
+	return dest
+}
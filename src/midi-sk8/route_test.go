@@ -0,0 +1,137 @@
+package main
+
+import (
+	"maps"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/widget"
+	"gitlab.com/gomidi/midi/v2"
+
+	"midi-sk8/internal/tpl"
+)
+
+func listenUDP(t *testing.T) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil { t.Fatal(err) }
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+func readUDP(t *testing.T, pc net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 1500)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil { t.Fatal(err) }
+	return string(buf[:n])
+}
+
+func TestRouterCachesConnections(t *testing.T) {
+	def, other := listenUDP(t), listenUDP(t)
+	r := newRouter(def.LocalAddr().String(), time.Second)
+	defer r.Close()
+	for range 3 {
+		if _, err := r.WriteTo([]byte("x"), other.LocalAddr().String()); err != nil { t.Fatal(err) }
+	}
+	if _, err := r.Write([]byte("manual")); err != nil { t.Fatal(err) }
+	if len(r.conns) != 2 { t.Errorf("router holds %d connections, want one per address (2)", len(r.conns)) }
+	for range 3 {
+		if got := readUDP(t, other); got != "x" { t.Errorf("routed payload = %q, want x", got) }
+	}
+	if got := readUDP(t, def); got != "manual" { t.Errorf("default destination got %q, want manual", got) }
+	r.Close()
+	if len(r.conns) != 0 { t.Errorf("Close left %d connections", len(r.conns)) }
+}
+
+func TestRouterLimit(t *testing.T) {
+	r := newRouter("127.0.0.1:9", time.Second)
+	defer r.Close()
+	for port := 20000; port < 20000+maxRoutes; port++ {
+		if _, err := r.WriteTo(nil, net.JoinHostPort("127.0.0.1", strconv.Itoa(port))); err != nil { t.Fatal(err) }
+	}
+	if _, err := r.WriteTo(nil, "127.0.0.1:30000"); err == nil { t.Errorf("destination %d was dialled, want the %d limit", maxRoutes+1, maxRoutes) }
+}
+
+func TestDestination(t *testing.T) {
+	s := &AppState{chanNumbers: widget.NewSelect([]string{"1-16", "0-15"}, nil)}
+	s.chanNumbers.SetSelected("1-16")
+	for _, tc := range []struct {
+		tpl, ports string
+		msg        midi.Message
+		want       string
+	}{
+		{"127.0.0.1:{9000+$c}", "", midi.NoteOn(2, 60, 100), "127.0.0.1:9003"},
+		{"10.0.0.$c:9000", "", midi.ControlChange(0, 7, 1), "10.0.0.1:9000"},
+		{"127.0.0.1:{9000+$c}", "note-on: 9100", midi.NoteOn(2, 60, 100), "127.0.0.1:9100"},
+		// a static address is only routed for the port overrides and otherwise stays put
+		{"127.0.0.1:9000", "cc: 9002", midi.NoteOn(0, 60, 100), "127.0.0.1:9000"},
+		{"127.0.0.1:9000", "cc: 9002", midi.ControlChange(0, 7, 1), "127.0.0.1:9002"},
+	} {
+		ports, err := parsePorts(tc.ports)
+		if err != nil { t.Fatal(err) }
+		s.routeTpl, s.ports = tc.tpl, ports
+		if got := s.destination(tc.msg); got != tc.want { t.Errorf("destination(%q, %q, %v) = %q, want %q", tc.tpl, tc.ports, tc.msg, got, tc.want) }
+	}
+	// the payload clamp does not reach the address
+	s.clamp = &tpl.Range{Min: 0, Max: 127}
+	s.routeTpl, s.ports = "127.0.0.1:{9000+$c}", nil
+	if got := s.destination(midi.NoteOn(2, 60, 100)); got != "127.0.0.1:9003" { t.Errorf("with a 0-127 clamp routed to %q, want 127.0.0.1:9003", got) }
+	s.clamp = nil
+	s.chanNumbers.SetSelected("0-15")
+	s.routeTpl, s.ports = "127.0.0.1:{9000+$c}", nil
+	if got := s.destination(midi.NoteOn(2, 60, 100)); got != "127.0.0.1:9002" { t.Errorf("0-15 numbering routed to %q, want 127.0.0.1:9002", got) }
+}
+
+func TestRouted(t *testing.T) {
+	for dest, want := range map[string]bool{"127.0.0.1:9000": false, "host:{9000+$c}": true, "10.0.0.$c:9000": true} {
+		if got := routed(dest); got != want { t.Errorf("routed(%q) = %v, want %v", dest, got, want) }
+	}
+}
+
+func TestParsePorts(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"note-on: 9001, cc: 9002", map[string]string{"note-on": "9001", "cc": "9002"}, false},
+		{" cc:9002 ,", map[string]string{"cc": "9002"}, false},
+		{"cc 9002", nil, true},
+		{"nope: 9002", nil, true},
+		{"cc: 0", nil, true},
+		{"cc: 65536", nil, true},
+		{"cc: x", nil, true},
+	} {
+		got, err := parsePorts(tc.text)
+		if (err != nil) != tc.wantErr || !tc.wantErr && !maps.Equal(got, tc.want) { t.Errorf("parsePorts(%q) = %v, %v, want %v", tc.text, got, err, tc.want) }
+	}
+}
+
+// each kind goes to its own port over one connection per destination, and the rest to
+// the default port
+func TestSendPerKindPorts(t *testing.T) {
+	def, cc := listenUDP(t), listenUDP(t)
+	_, ccPort, _ := net.SplitHostPort(cc.LocalAddr().String())
+	s := newAppState()
+	s.routeTpl, s.ports = def.LocalAddr().String(), map[string]string{"cc": ccPort}
+	s.router = newRouter(s.resolveDest(msgVars(0, 0, 0, 0)), time.Second)
+	defer s.router.Close()
+	s.udpOut, s.armed, s.repaint = newBatcher(s.router, 0, 0, nil), true, time.Hour
+	s.sinks = []*sink{s.mainSink()}
+	for range 3 {
+		if err := s.sendMsg("note", midi.NoteOn(0, 60, 100)); err != nil { t.Fatal(err) }
+		if err := s.sendMsg("cc", midi.ControlChange(0, 7, 1)); err != nil { t.Fatal(err) }
+	}
+	if err := s.send("manual"); err != nil { t.Fatal(err) }
+	for range 3 {
+		if got := readUDP(t, def); got != "note" { t.Errorf("default port got %q, want note", got) }
+		if got := readUDP(t, cc); got != "cc" { t.Errorf("cc port got %q, want cc", got) }
+	}
+	if got := readUDP(t, def); got != "manual" { t.Errorf("default port got %q, want manual", got) }
+	if len(s.router.conns) != 2 { t.Errorf("router holds %d connections, want one per destination (2)", len(s.router.conns)) }
+}
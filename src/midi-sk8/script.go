@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scriptStep is one line of a manual script: a payload to send or a pause
+type scriptStep struct {
+	payload string
+	wait    time.Duration
+}
+
+// parseScript turns each non-blank line into a send followed by the default delay;
+// a ";wait MS" line pauses for MS milliseconds instead
+func parseScript(text string, delay time.Duration) ([]scriptStep, error) {
+	var steps []scriptStep
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" { continue }
+		if arg, ok := strings.CutPrefix(line, ";wait"); ok {
+			ms, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil || ms < 0 { return nil, fmt.Errorf("line %d: expected \";wait MS\"", i+1) }
+			steps = append(steps, scriptStep{wait: time.Duration(ms) * time.Millisecond})
+			continue
+		}
+		steps = append(steps, scriptStep{payload: line}, scriptStep{wait: delay})
+	}
+	return steps, nil
+}
+
+// runScript sends the script off the UI thread; running it again or ending the session stops it
+func (s *AppState) runScript() {
+	ms, err := parseNonNegative("script delay", s.scriptMs.Text)
+	if err != nil { s.appendLog(s.udpLog, "! "+err.Error()); return }
+	steps, err := parseScript(s.scriptEntry.Text, time.Duration(ms)*time.Millisecond)
+	if err != nil { s.appendLog(s.udpLog, "! script "+err.Error()); return }
+	s.cancelScript()
+	stop := make(chan struct{})
+	s.mu.Lock(); s.stopScript = func() { close(stop) }; s.mu.Unlock()
+	ctx := s.session()
+	s.workers.Go(func() {
+		for n, st := range steps {
+			if st.payload == "" {
+				select {
+				case <-stop: s.appendLog(s.udpLog, "! script stopped"); return
+				case <-ctx.Done(): s.appendLog(s.udpLog, "! script stopped"); return
+				case <-time.After(st.wait):
+				}
+				continue
+			}
+			if err := s.send(st.payload); err != nil { s.appendLog(s.udpLog, fmt.Sprintf("! script step %d: %v", n+1, err)); return }
+			s.appendLog(s.udpLog, "> "+st.payload)
+			s.flashOut()
+		}
+	})
+}
+
+// cancelScript stops a running script, if any
+func (s *AppState) cancelScript() {
+	s.mu.Lock()
+	stop := s.stopScript
+	s.stopScript = nil
+	s.mu.Unlock()
+	if stop != nil { stop() }
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// serialRetry limits how often a lost serial device is reopened
+const serialRetry = time.Second
+
+// serialOut writes to a serial device and reopens it after a failed write,
+// so unplugging and replugging a board does not require reconnecting.
+// log is told about each loss and recovery.
+type serialOut struct {
+	mu      sync.Mutex
+	name    string
+	mode    *serial.Mode
+	port    serial.Port
+	lastTry time.Time
+	log     func(line string, ok bool)
+}
+
+func parseBaud(text string) (int, error) {
+	baud, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || baud <= 0 { return 0, fmt.Errorf("baud rate must be a positive number") }
+	return baud, nil
+}
+
+// openSerial opens the device up front so a bad port name is reported at connect time
+func openSerial(name string, baud int, log func(line string, ok bool)) (*serialOut, error) {
+	o := &serialOut{name: name, mode: &serial.Mode{BaudRate: baud}, log: log}
+	port, err := serial.Open(name, o.mode)
+	if err != nil { return nil, fmt.Errorf("open %s: %w", name, err) }
+	o.port = port
+	return o, nil
+}
+
+func (o *serialOut) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.port == nil {
+		if time.Since(o.lastTry) < serialRetry { return 0, fmt.Errorf("%s is not open", o.name) }
+		o.lastTry = time.Now()
+		port, err := serial.Open(o.name, o.mode)
+		if err != nil { return 0, err }
+		o.port = port
+		o.log(fmt.Sprintf("Reopened serial port %s", o.name), true)
+	}
+	n, err := o.port.Write(p)
+	if err != nil {
+		o.port.Close()
+		o.port, o.lastTry = nil, time.Now()
+		o.log(fmt.Sprintf("Serial write to %s failed: %v (will retry)", o.name, err), false)
+	}
+	return n, err
+}
+
+func (o *serialOut) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.port == nil { return nil }
+	err := o.port.Close()
+	o.port = nil
+	return err
+}
+
+// serialPorts lists the devices offered in the serial port picker
+func serialPorts() []string {
+	ports, err := serial.GetPortsList()
+	if err != nil { return nil }
+	return ports
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+)
+
+// sessionReport is a plain-text header describing this setup, followed by both logs
+func (s *AppState) sessionReport() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# midi-sk8 session, exported %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "version:   %s\n", version)
+	fmt.Fprintf(&b, "os:        %s/%s (%s)\n", runtime.GOOS, runtime.GOARCH, runtime.Version())
+	drv := "none"
+	if d := s.driver(); d != nil { drv = d.String() }
+	fmt.Fprintf(&b, "driver:    %s\n", drv)
+	fmt.Fprintf(&b, "midi-in:   %s (virtual %v %q)\n", s.midiSelect.Selected, s.virtualIn.Checked, s.virtualName.Text)
+	fmt.Fprintf(&b, "output:    %s udp %s:%s serial %s @ %s socket %s\n", s.outMode.Selected, s.addrEntry.Text, s.portEntry.Text, s.serialPort.Text, s.serialBaud.Text, s.sockPath.Text)
+	fmt.Fprintf(&b, "framing:   %s, batch %s bytes / %s ms, channels %s\n", s.framing.Selected, s.batchSize.Text, s.batchMs.Text, s.chanNumbers.Selected)
+	fmt.Fprintf(&b, "status:    %s\n", s.statusLabel.Text)
+	p := s.snapshot()
+	var keys []string
+	for k := range p.Templates { keys = append(keys, k) }
+	sort.Strings(keys)
+	b.WriteString("\n## templates\n")
+	for _, k := range keys { fmt.Fprintf(&b, "%s: %s\n", k, p.Templates[k]) }
+	if len(p.CCMap) > 0 { b.WriteString("cc-map:\n" + formatCCMap(p.CCMap) + "\n") }
+	if p.Invert != "" { fmt.Fprintf(&b, "invert: %s\n", p.Invert) }
+	if p.Macros != "" { b.WriteString("macros:\n" + p.Macros + "\n") }
+	b.WriteString("\n## midi in\n" + s.midiLog.Text)
+	b.WriteString("\n## udp out\n" + s.udpLog.Text)
+	return b.String()
+}
+
+// showSessionExport asks for a destination and writes the session report there
+func (s *AppState) showSessionExport(w fyne.Window) {
+	d := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+		if err != nil { dialog.ShowError(err, w); return }
+		if wc == nil { return }
+		defer wc.Close()
+		if _, err := wc.Write([]byte(s.sessionReport())); err != nil { dialog.ShowError(err, w) }
+	}, w)
+	d.SetFileName("midi-sk8-session-" + time.Now().Format("20060102-150405") + ".txt")
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".txt"}))
+	d.Show()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// shortcut is one Ctrl/Cmd+key binding for a core action
+type shortcut struct {
+	key   fyne.KeyName
+	label string
+	run   func()
+}
+
+// addShortcuts binds each action on the window canvas. A focused entry gets shortcuts
+// before the canvas does, so none of these fire while typing.
+func addShortcuts(w fyne.Window, list []shortcut) {
+	for _, sc := range list {
+		w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: sc.key, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) { sc.run() })
+	}
+}
+
+// showShortcuts lists the bindings in a dialog
+func showShortcuts(w fyne.Window, list []shortcut) {
+	mod := "Ctrl+"
+	if runtime.GOOS == "darwin" { mod = "Cmd+" }
+	var lines []string
+	for _, sc := range list { lines = append(lines, fmt.Sprintf("%s%s\t%s", mod, sc.key, sc.label)) }
+	lines = append(lines, "", "Shortcuts are ignored while an entry has focus.")
+	dialog.ShowInformation("Keyboard shortcuts", strings.Join(lines, "\n"), w)
+}
+
+// panicNotes releases every held note right away
+func (s *AppState) panicNotes() {
+	s.releaseHeld()
+	s.appendLog(s.udpLog, "! panic: released held notes")
+}
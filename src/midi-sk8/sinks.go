@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// sinkProtos are the transports an extra output can use; Unix sockets are not offered on Windows
+func sinkProtos() []string {
+	if runtime.GOOS == "windows" { return []string{"udp", "tcp"} }
+	return []string{"udp", "tcp", "unixgram", "unix"}
+}
+
+// sinkConf is one output with its own transport, destination and framing. An extra
+// output is sent every payload, wherever the address template routes it on the main one.
+type sinkConf struct{ proto, dest, framing string }
+
+func (c sinkConf) String() string { return c.proto + " " + c.dest }
+
+// encode and parseSink store a sinkConf as "proto|dest|framing" in the preferences
+func (c sinkConf) encode() string { return c.proto + "|" + c.dest + "|" + c.framing }
+
+func parseSink(text string) (sinkConf, bool) {
+	f := strings.Split(text, "|")
+	if len(f) != 3 { return sinkConf{}, false }
+	return sinkConf{f[0], f[1], f[2]}, true
+}
+
+func (c sinkConf) dial(timeout time.Duration) (net.Conn, error) {
+	if c.proto == "udp" || c.proto == "tcp" { return net.DialTimeout(c.proto, c.dest, timeout) }
+	return dialUnix(c.proto, c.dest, timeout)
+}
+
+// errSinkDown is returned while a lost extra output waits to be redialled
+var errSinkDown = errors.New("waiting to reconnect")
+
+// sink is an open output of the session. Every payload is framed for each sink and goes
+// through the rate limit and the output delay once, then write hands it to all of them:
+// the main output, set above the templates, is written in line, and each extra output
+// has its own queue and goroutine, so a slow or failing one never holds up the others.
+type sink struct {
+	conf    sinkConf
+	main    bool
+	queue   chan []byte
+	conn    net.Conn // owned by runSink
+	dialed  time.Time
+	failing bool
+}
+
+// write sends one framed payload, redialling a lost stream at most once per serialRetry
+func (k *sink) write(data []byte, timeout time.Duration) error {
+	if k.conn == nil {
+		if time.Since(k.dialed) < serialRetry { return errSinkDown }
+		k.dialed = time.Now()
+		c, err := k.conf.dial(timeout)
+		if err != nil { return err }
+		k.conn = c
+	}
+	_, err := k.conn.Write(data)
+	if err != nil && k.conf.proto != "udp" && k.conf.proto != "unixgram" { k.conn.Close(); k.conn = nil }
+	return err
+}
+
+// framed is a payload framed for one sink
+type framed struct {
+	to   *sink
+	data []byte
+}
+
+// mainSink is the main output as a sink; its destination is the output set above the
+// templates, so only the framing is kept
+func (s *AppState) mainSink() *sink { return &sink{conf: sinkConf{framing: s.framing.Selected}, main: true} }
+
+// openSinks sets up the main output and dials every configured extra output for the
+// session; one that cannot be opened is logged and skipped rather than failing the connect
+func (s *AppState) openSinks(ctx context.Context) {
+	open := []*sink{s.mainSink()}
+	for _, r := range s.sinkRows {
+		c := r.conf()
+		if c.dest == "" { continue }
+		conn, err := c.dial(s.dialTimeout())
+		if err != nil { s.appendLog(s.midiLog, fmt.Sprintf("Error: output %s: %v", c, err)); continue }
+		k := &sink{conf: c, queue: make(chan []byte, maxQueued), conn: conn, dialed: time.Now()}
+		open = append(open, k)
+		s.workers.Go(func() { s.runSink(ctx, k) })
+	}
+	s.mu.Lock(); s.sinks = open; s.mu.Unlock()
+}
+
+// runSink writes queued payloads to one extra output until the session ends, logging
+// when it starts failing and when it recovers
+func (s *AppState) runSink(ctx context.Context, k *sink) {
+	for {
+		var data []byte
+		select {
+		case <-ctx.Done(): return
+		case data = <-k.queue:
+		}
+		err := s.writeSink(k, data)
+		if errors.Is(err, errOversize) { continue } // logged already, and no sign of a failing output
+		switch {
+		case err != nil && !k.failing: s.appendLog(s.udpLog, fmt.Sprintf("! output %s: %v", k.conf, err))
+		case err == nil && k.failing: s.appendLog(s.udpLog, fmt.Sprintf("! output %s: sending again", k.conf))
+		}
+		k.failing = err != nil
+	}
+}
+
+// writeSink writes one framed payload to an extra output, with the same datagram size
+// handling and counting as the main output gets in writeMain
+func (s *AppState) writeSink(k *sink, data []byte) error {
+	var err error
+	if limit := s.maxDatagram(); k.conf.proto == "udp" && len(data) > limit { err = s.oversize(k, data, "", limit) } else { err = k.write(data, s.dialTimeout()) }
+	if err == nil { s.countSent(len(data)) }
+	return err
+}
+
+// frameAll frames a payload for every sink of the session, each in its own framing; a
+// payload one of them cannot carry goes to none
+func (s *AppState) frameAll(payload string) ([]framed, error) {
+	s.mu.Lock()
+	sinks := s.sinks
+	s.mu.Unlock()
+	out := make([]framed, 0, len(sinks))
+	for _, k := range sinks {
+		data, err := frame(k.conf.framing, []byte(payload))
+		if err != nil && !k.main { err = fmt.Errorf("output %s: %w", k.conf, err) }
+		if err != nil { return nil, err }
+		out = append(out, framed{k, data})
+	}
+	return out, nil
+}
+
+// closeSinks writes what the extra outputs still hold, such as the releases of held
+// notes, and closes them once their goroutines have stopped
+func (s *AppState) closeSinks() {
+	s.mu.Lock()
+	sinks := s.sinks
+	s.sinks = nil
+	s.mu.Unlock()
+	for _, k := range sinks {
+		if k.main { continue }
+		for len(k.queue) > 0 { s.writeSink(k, <-k.queue) }
+		if k.conn != nil { k.conn.Close() }
+	}
+}
+
+// sinkRow is the settings row editing one extra output
+type sinkRow struct {
+	proto, framing *widget.Select
+	dest           *widget.Entry
+}
+
+func (r *sinkRow) conf() sinkConf {
+	return sinkConf{r.proto.Selected, strings.TrimSpace(r.dest.Text), r.framing.Selected}
+}
+
+// sinkPrefs encodes the extra outputs for savePrefs
+func (s *AppState) sinkPrefs() []string {
+	var out []string
+	for _, r := range s.sinkRows { out = append(out, r.conf().encode()) }
+	return out
+}
+
+// newSinkEditor lists the extra outputs saved last time, with buttons to add and
+// remove them; changes apply on the next connect
+func (s *AppState) newSinkEditor(saved []string) fyne.CanvasObject {
+	box := container.NewVBox()
+	var rebuild func()
+	add := func(c sinkConf) {
+		r := &sinkRow{proto: widget.NewSelect(sinkProtos(), nil), dest: widget.NewEntry(), framing: widget.NewSelect(framings, nil)}
+		// SetSelected ignores values not offered, so a blank or foreign saved value keeps the default
+		r.proto.SetSelected("udp"); r.proto.SetSelected(c.proto)
+		r.framing.SetSelected("raw"); r.framing.SetSelected(c.framing)
+		r.dest.SetPlaceHolder("host:port or socket path")
+		r.dest.SetText(c.dest)
+		s.sinkRows = append(s.sinkRows, r)
+	}
+	rebuild = func() {
+		objs := make([]fyne.CanvasObject, 0, len(s.sinkRows)+1)
+		for _, r := range s.sinkRows {
+			del := widget.NewButtonWithIcon("", theme.ContentRemoveIcon(), func() {
+				s.sinkRows = slices.DeleteFunc(s.sinkRows, func(x *sinkRow) bool { return x == r })
+				rebuild()
+			})
+			objs = append(objs, container.NewBorder(nil, nil, r.proto, container.NewHBox(r.framing, del), r.dest))
+		}
+		objs = append(objs, widget.NewButtonWithIcon("add output", theme.ContentAddIcon(), func() { add(sinkConf{}); rebuild() }))
+		box.Objects = objs
+		box.Refresh()
+	}
+	for _, text := range saved {
+		if c, ok := parseSink(text); ok { add(c) }
+	}
+	rebuild()
+	return box
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// readNothing fails if anything arrives on pc within a short wait
+func readNothing(t *testing.T, pc net.PacketConn) {
+	t.Helper()
+	buf := make([]byte, 1500)
+	pc.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, _, err := pc.ReadFrom(buf); err == nil { t.Errorf("got %q, want nothing", buf[:n]) }
+}
+
+// an extra output gets what the main one gets, in its own framing, after the same tag,
+// rate limit and delay, and the releases sent on disconnect
+func TestSinksSharePipeline(t *testing.T) {
+	s := newAppState()
+	s.framing.SetSelected("null")
+	r := testOutput(s)
+	extra := listenUDP(t)
+	s.newSinkEditor([]string{"udp|" + extra.LocalAddr().String() + "|newline"})
+	ctx, end := context.WithCancel(context.Background())
+	s.sessionCtx, s.endSession = ctx, end
+	s.openSinks(ctx)
+	if len(s.sinks) != 2 || !s.sinks[0].main { t.Fatalf("sinks %v, want the main output and one extra", s.sinks) }
+
+	s.seqTags.SetChecked(true)
+	if err := s.send("a"); err != nil { t.Fatal(err) }
+	if got := readUDP(t, extra); got != "a #1\n" { t.Errorf("extra output got %q, want a #1 framed with a newline", got) }
+	if got := r.got(); !slices.Equal(got, []string{"a #1\x00"}) { t.Errorf("main output got %q", got) }
+	s.seqTags.SetChecked(false)
+
+	s.bucket = newBucket(1, 1, time.Now())
+	if err := s.send("b"); err != nil { t.Fatal(err) }
+	if err := s.send("c"); err != errRateLimited { t.Errorf("send past the limit = %v, want %v", err, errRateLimited) }
+	if got := readUDP(t, extra); got != "b\n" { t.Errorf("extra output got %q, want b", got) }
+	readNothing(t, extra)
+	s.bucket = nil
+
+	q := make(chan delayed, 8)
+	s.delays, s.delay = q, 50*time.Millisecond
+	s.workers.Go(func() { s.runDelays(ctx, q) })
+	start := time.Now()
+	if err := s.send("d"); err != nil { t.Fatal(err) }
+	if got := readUDP(t, extra); got != "d\n" { t.Errorf("extra output got %q, want d", got) }
+	if d := time.Since(start); d < s.delay { t.Errorf("extra output got the payload after %v, before the %v delay", d, s.delay) }
+	s.mu.Lock(); s.delays = nil; s.mu.Unlock()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, writes := s.sentCounts(); writes == 6 { break }
+	}
+	if _, writes := s.sentCounts(); writes != 6 { t.Errorf("counted %d writes, want 3 on each output", writes) }
+
+	s.noteOnTpl.SetText("")
+	s.noteOffTpl.SetText("off n$n")
+	s.dispatch(midi.NoteOn(0, 60, 100), time.Now())
+	s.disconnect()
+	if got := readUDP(t, extra); got != "off n60\n" { t.Errorf("extra output got %q on disconnect, want the release", got) }
+}
+
+// a payload one output cannot frame goes to none of them
+func TestSinksFramingFailure(t *testing.T) {
+	s := newAppState()
+	r := testOutput(s)
+	k := &sink{conf: sinkConf{"udp", "127.0.0.1:9", "length-prefix"}, queue: make(chan []byte, 1)}
+	s.sinks = append(s.sinks, k)
+	if err := s.send(strings.Repeat("x", 0x10000)); err == nil || !strings.Contains(err.Error(), "output udp 127.0.0.1:9") { t.Errorf("send = %v, want the extra output's framing error", err) }
+	if len(r.got()) != 0 || len(k.queue) != 0 { t.Errorf("sent %d to the main output and queued %d for the extra", len(r.got()), len(k.queue)) }
+	if err := s.send("x"); err != nil || len(r.got()) != 1 || len(k.queue) != 1 { t.Errorf("a payload both can frame: %v", err) }
+}
+
+// a UDP extra output gets the datagram size handling of the main one. Its queue is
+// written here rather than by runSink, whose log lines would race the test's reads
+// under the test driver.
+func TestSinkOversize(t *testing.T) {
+	for _, tc := range []struct{ mode, log string }{
+		{"drop", "! dropped 10-byte payload, over the 8-byte datagram limit for output udp "},
+		{"split", "! split 10-byte payload into 2 datagrams of up to 8 bytes for output udp "},
+	} {
+		s := newAppState()
+		r := testOutput(s)
+		pc := listenUDP(t)
+		c := sinkConf{"udp", pc.LocalAddr().String(), "raw"}
+		conn, err := c.dial(time.Second)
+		if err != nil { t.Fatal(err) }
+		defer conn.Close()
+		k := &sink{conf: c, queue: make(chan []byte, 1), conn: conn}
+		s.sinks = append(s.sinks, k)
+		s.maxUDP.SetText("8")
+		s.onOversize.SetSelected(tc.mode)
+		if err := s.send("0123456789"); err != nil { t.Fatalf("%s: send of a payload too big only for the extra output: %v", tc.mode, err) }
+		if got := r.got(); len(got) != 1 { t.Errorf("%s: main output got %q", tc.mode, got) }
+		err = s.writeSink(k, <-k.queue)
+		if tc.mode == "drop" {
+			if err != errOversize { t.Errorf("drop: writeSink = %v, want %v", err, errOversize) }
+			readNothing(t, pc)
+		} else if a, b := readUDP(t, pc), readUDP(t, pc); err != nil || a != "01234567" || b != "89" { t.Errorf("split: %v, datagrams %q %q", err, a, b) }
+		if got := s.logRingFor(s.udpLog).text(); got != tc.log+c.dest+"\n" { t.Errorf("%s: log %q", tc.mode, got) }
+	}
+}
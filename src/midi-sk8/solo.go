@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strconv"
+
+	"fyne.io/fyne/v2/widget"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// noSolo is the solo choice that forwards every channel
+const noSolo = "solo: none"
+
+// soloOptions are the solo choices, with channels shown from base
+func soloOptions(base uint8) []string {
+	opts := []string{noSolo}
+	for ch := range 16 { opts = append(opts, "solo: "+strconv.Itoa(ch+int(base))) }
+	return opts
+}
+
+// newSoloSelect builds the header drop-down that, while set, forwards only one MIDI
+// channel, numbered as the channel setting shows them. It is not saved, so a restart
+// always forwards everything.
+func (s *AppState) newSoloSelect() *widget.Select {
+	var sel *widget.Select
+	sel = widget.NewSelect(soloOptions(s.channelBase()), func(opt string) {
+		solo := -1
+		for i, o := range sel.Options[1:] { if o == opt { solo = i } }
+		s.mu.Lock(); s.solo = solo; s.mu.Unlock()
+	})
+	sel.SetSelected(noSolo)
+	s.soloSel = sel
+	return sel
+}
+
+// soloPass reports whether msg may be sent under the current solo; messages without a
+// channel, like timecode, always pass
+func (s *AppState) soloPass(msg midi.Message) bool {
+	var ch uint8
+	if !msg.GetChannel(&ch) { return true }
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.solo < 0 || int(ch) == s.solo
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// statKey identifies a controller, note or bend on one raw channel
+type statKey struct {
+	kind    string
+	ch, num uint8
+}
+
+// ctlStat is the observed range of one controller
+type ctlStat struct{ min, max, last int }
+
+var statColumns = []string{"type", "ch", "num", "min", "max", "last"}
+
+// trackStats records the raw value of notes, controllers and pitch bend for calibration
+func (s *AppState) trackStats(msg midi.Message) {
+	var ch, key, vel, cc, val uint8
+	var bend int16
+	var abs uint16
+	var k statKey
+	var v int
+	switch {
+	case msg.GetNoteStart(&ch, &key, &vel): k, v = statKey{"note", ch, key}, int(vel)
+	case msg.GetControlChange(&ch, &cc, &val): k, v = statKey{"cc", ch, cc}, int(val)
+	case msg.GetPitchBend(&ch, &bend, &abs): k, v = statKey{"pb", ch, 0}, int(abs)
+	default: return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil { s.stats = map[statKey]ctlStat{} }
+	st, seen := s.stats[k]
+	if !seen { st = ctlStat{v, v, v} }
+	st.min, st.max, st.last = min(st.min, v), max(st.max, v), v
+	s.stats[k] = st
+}
+
+// countSent adds one written payload to the output totals
+func (s *AppState) countSent(n int) {
+	s.mu.Lock(); s.sentBytes += n; s.sentWrites++; s.mu.Unlock()
+}
+
+// sentCounts reports the bytes and payloads written since connecting or the last reset
+func (s *AppState) sentCounts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sentBytes, s.sentWrites
+}
+
+// humanBytes shows a byte count in B, KB or MB
+func humanBytes(n float64) string {
+	switch {
+	case n >= 1<<20: return strconv.FormatFloat(n/(1<<20), 'f', 1, 64) + " MB"
+	case n >= 1<<10: return strconv.FormatFloat(n/(1<<10), 'f', 1, 64) + " KB"
+	}
+	return strconv.FormatFloat(n, 'f', 0, 64) + " B"
+}
+
+// statRows snapshots the stats as table rows, sorted by type, channel and number
+func (s *AppState) statRows() [][]string {
+	s.mu.Lock()
+	keys := make([]statKey, 0, len(s.stats))
+	for k := range s.stats { keys = append(keys, k) }
+	stats := make(map[statKey]ctlStat, len(s.stats))
+	for k, v := range s.stats { stats[k] = v }
+	s.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.kind != b.kind { return a.kind < b.kind }
+		if a.ch != b.ch { return a.ch < b.ch }
+		return a.num < b.num
+	})
+	rows := make([][]string, len(keys))
+	for i, k := range keys {
+		st := stats[k]
+		rows[i] = []string{k.kind, strconv.Itoa(int(k.ch + s.channelBase())), strconv.Itoa(int(k.num)),
+			strconv.Itoa(st.min), strconv.Itoa(st.max), strconv.Itoa(st.last)}
+	}
+	return rows
+}
+
+// showStats opens a window with the observed ranges, refreshed a few times a second
+func (s *AppState) showStats() {
+	w := fyne.CurrentApp().NewWindow("controller ranges")
+	var rows [][]string
+	t := widget.NewTable(
+		func() (int, int) { return len(rows), len(statColumns) },
+		func() fyne.CanvasObject { return widget.NewLabelWithStyle("", 0, fyne.TextStyle{Monospace: true}) },
+		func(id widget.TableCellID, o fyne.CanvasObject) { o.(*widget.Label).SetText(rows[id.Row][id.Col]) },
+	)
+	t.ShowHeaderRow = true
+	t.CreateHeader = func() fyne.CanvasObject { return widget.NewLabelWithStyle("", 0, fyne.TextStyle{Bold: true}) }
+	t.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Col >= 0 { o.(*widget.Label).SetText(statColumns[id.Col]) }
+	}
+	for i, cw := range []float32{50, 40, 50, 60, 60, 60} { t.SetColumnWidth(i, cw) }
+	dropped := widget.NewLabel("")
+	showDropped := func(out, in int) {
+		dropped.SetText("dropped: " + strconv.Itoa(out) + " by rate limit, " + strconv.Itoa(in) + " by input buffer")
+	}
+	showDropped(s.droppedCounts())
+	// the rate is taken over the last second, so a runaway bend shows up straight away
+	sent := widget.NewLabel("")
+	var lastBytes int
+	var lastAt time.Time
+	var rate float64
+	showSent := func(bytes, writes int, now time.Time) {
+		if lastAt.IsZero() || bytes < lastBytes { lastBytes, lastAt = bytes, now }
+		if secs := now.Sub(lastAt).Seconds(); secs >= 1 { rate, lastBytes, lastAt = float64(bytes-lastBytes)/secs, bytes, now }
+		via := "stream"
+		if s.datagrams() { via = "udp" }
+		sent.SetText("sent: " + humanBytes(float64(bytes)) + " in " + strconv.Itoa(writes) + " payloads over " + via + ", " + humanBytes(rate) + "/s")
+	}
+	bytes, writes := s.sentCounts()
+	showSent(bytes, writes, time.Now())
+	rtt := widget.NewLabel(s.rttSummary())
+	reset := widget.NewButton("reset", func() {
+		s.mu.Lock(); s.stats, s.dropped, s.inDropped, s.sentBytes, s.sentWrites = nil, 0, 0, 0, 0; s.mu.Unlock()
+		rows = nil; t.Refresh(); showDropped(0, 0)
+		lastAt, rate = time.Time{}, 0; showSent(0, 0, time.Now())
+		s.mu.Lock(); if s.rtt != nil { s.rtt = &roundTrip{} }; s.mu.Unlock()
+		rtt.SetText(s.rttSummary())
+	})
+	w.SetContent(container.NewBorder(nil, container.NewBorder(nil, nil, container.NewVBox(dropped, sent, rtt), reset), nil, nil, t))
+	w.Resize(fyne.NewSize(360, 400))
+
+	done := make(chan struct{})
+	w.SetOnClosed(func() { close(done) })
+	go func() {
+		tick := time.NewTicker(250 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-done: return
+			case <-tick.C:
+				r := s.statRows()
+				out, in := s.droppedCounts()
+				bytes, writes := s.sentCounts()
+				now, trip := time.Now(), s.rttSummary()
+				fyne.Do(func() { rows = r; t.Refresh(); showDropped(out, in); showSent(bytes, writes, now); rtt.SetText(trip) })
+			}
+		}
+	}()
+	w.Show()
+}
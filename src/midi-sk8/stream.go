@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// streamFormats are the encodings -midi-stdin reads
+var streamFormats = []string{"hex", "raw"}
+
+// dataLen is how many data bytes follow a status byte, or -1 for SysEx, which runs to F7
+func dataLen(status byte) int {
+	switch {
+	case status < 0xC0, status >= 0xE0 && status < 0xF0, status == 0xF2: return 2
+	case status < 0xE0, status == 0xF1, status == 0xF3: return 1
+	case status == 0xF0: return -1
+	}
+	return 0
+}
+
+// splitter turns a MIDI byte stream into messages. Channel messages may use running
+// status and real-time bytes may appear anywhere, even inside another message.
+type splitter struct {
+	running byte
+	msg     []byte
+	emit    func(midi.Message)
+}
+
+func (p *splitter) feed(b byte) {
+	switch {
+	case b >= 0xF8: p.emit(midi.Message{b}); return
+	case b == 0xF7 && len(p.msg) > 0 && p.msg[0] == 0xF0: p.emit(append(p.msg, b)); p.msg = nil; return
+	case b >= 0x80:
+		p.msg, p.running = []byte{b}, 0
+		if b < 0xF0 { p.running = b }
+	case len(p.msg) == 0:
+		// a data byte with nothing to belong to is dropped unless running status applies
+		if p.running == 0 { return }
+		p.msg = []byte{p.running, b}
+	default: p.msg = append(p.msg, b)
+	}
+	if n := dataLen(p.msg[0]); n >= 0 && len(p.msg) == n+1 { p.emit(p.msg); p.msg = nil }
+}
+
+// readStream splits MIDI read from r into messages as they arrive, passing each to emit.
+// raw is the bytes as sent on the wire. hex is text with one or more hex bytes per
+// token ("90 3c 64" or "903c64"), an optional 0x prefix, and # starting a comment.
+func readStream(r io.Reader, format string, emit func(midi.Message)) error {
+	p := &splitter{emit: emit}
+	if format == "raw" {
+		br := bufio.NewReader(r)
+		for {
+			b, err := br.ReadByte()
+			if err == io.EOF { return nil }
+			if err != nil { return err }
+			p.feed(b)
+		}
+	}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		for _, tok := range strings.Fields(line) {
+			bs, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(tok), "0x"))
+			if err != nil { return fmt.Errorf("line %d: %q is not hex bytes", n, tok) }
+			for _, b := range bs { p.feed(b) }
+		}
+	}
+	return sc.Err()
+}
+
+// streamSource is a dryRun source reading format from r
+func streamSource(r io.Reader, format string) func(emit func(midi.Message)) error {
+	return func(emit func(midi.Message)) error { return readStream(r, format, emit) }
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestReadStream(t *testing.T) {
+	for _, tc := range []struct {
+		name, format, in string
+		want             []string // hex of each message
+		wantErr          string
+	}{
+		{"hex", "hex", "90 3c 64\n80 3c 00", []string{"903c64", "803c00"}, ""},
+		{"packed tokens and 0x", "hex", "903c64 0xB0 0x07 0x7f", []string{"903c64", "b0077f"}, ""},
+		{"comments", "hex", "# a note\n90 3c 64 # on\n", []string{"903c64"}, ""},
+		{"running status", "hex", "90 3c 64 3e 64 40 00", []string{"903c64", "903e64", "904000"}, ""},
+		{"two-byte messages", "hex", "c0 05 06 d0 40", []string{"c005", "c006", "d040"}, ""},
+		{"real-time inside a message", "hex", "90 f8 3c 64", []string{"f8", "903c64"}, ""},
+		{"sysex", "hex", "f0 7e 7f 06 01 f7 90 3c 64", []string{"f07e7f0601f7", "903c64"}, ""},
+		{"system common ends running status", "hex", "90 3c 64 f1 23 3c 64", []string{"903c64", "f123"}, ""},
+		{"stray data bytes", "hex", "3c 64 90 3c 64", []string{"903c64"}, ""},
+		{"bad hex", "hex", "90 3c\n9z", nil, "line 2"},
+		{"odd digits", "hex", "903", nil, "line 1"},
+		{"raw", "raw", "\x90\x3c\x64\x3e\x64\xf8\xb0\x07\x7f", []string{"903c64", "903e64", "f8", "b0077f"}, ""},
+	} {
+		var got []string
+		err := readStream(strings.NewReader(tc.in), tc.format, func(m midi.Message) { got = append(got, fmt.Sprintf("%x", m.Bytes())) })
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) { t.Errorf("%s: error = %v, want %q", tc.name, err, tc.wantErr) }
+			continue
+		}
+		if err != nil || !slices.Equal(got, tc.want) { t.Errorf("%s: got %v, %v, want %v", tc.name, got, err, tc.want) }
+	}
+}
+
+// -midi-stdin runs the bytes through the same pipeline as the canned dry run
+func TestDryRunStream(t *testing.T) {
+	preset := writePreset(t, `{"version":1,"templates":{"note-on":"n$n l$v","note-off":"off n$n","cc":"c$n $v"}}`)
+	var out, errOut bytes.Buffer
+	if code := dryRun(preset, streamSource(strings.NewReader("90 3c 64 3e 40\nb0 07 7f\n80 3c 00\n"), "hex"), &out, &errOut); code != 0 { t.Fatalf("exit code %d: %s", code, errOut.String()) }
+	want := "NoteOn channel: 0 key: 60 velocity: 100 -> n60 l100\n" +
+		"NoteOn channel: 0 key: 62 velocity: 64 -> n62 l64\n" +
+		"ControlChange channel: 0 controller: 7 value: 127 -> c7 127\n" +
+		"NoteOff channel: 0 key: 60 -> off n60\n"
+	if out.String() != want { t.Errorf("output\n%s\nwant\n%s", out.String(), want) }
+	out.Reset(); errOut.Reset()
+	if code := dryRun(preset, streamSource(strings.NewReader("90 3c zz"), "hex"), &out, &errOut); code != 2 || !strings.Contains(errOut.String(), "midi input: line 1") { t.Errorf("bad input: exit code %d, errors %q", code, errOut.String()) }
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"gitlab.com/gomidi/midi/v2/drivers"
+)
+
+// sysexPrefix marks a payload, typed or rendered from a template, as hex SysEx for the
+// MIDI output instead of text for the network, e.g. "sysex F0 7E 7F 06 01 F7"
+const sysexPrefix = "sysex "
+
+// noMidiOut is the midi-out choice that leaves MIDI output closed
+const noMidiOut = "none"
+
+// parseSysEx decodes hex bytes, spaced or not, and checks they form one complete SysEx
+// message: F0 first, F7 last and only 7-bit data between them
+func parseSysEx(text string) ([]byte, error) {
+	digits := strings.Join(strings.Fields(text), "")
+	if len(digits)%2 != 0 { return nil, fmt.Errorf("sysex: odd number of hex digits") }
+	b, err := hex.DecodeString(digits)
+	if err != nil { return nil, fmt.Errorf("sysex: %v", err) }
+	if len(b) < 2 || b[0] != 0xF0 { return nil, fmt.Errorf("sysex: must start with F0") }
+	if b[len(b)-1] != 0xF7 { return nil, fmt.Errorf("sysex: missing F7 terminator") }
+	for i, x := range b[1 : len(b)-1] {
+		if x > 0x7F { return nil, fmt.Errorf("sysex: byte %d is %02X, data bytes must be below 80", i+1, x) }
+	}
+	return b, nil
+}
+
+// outPorts lists the outputs of the chosen driver
+func (s *AppState) outPorts() []drivers.Out {
+	d := s.driver()
+	if d == nil { return nil }
+	outs, err := d.Outs()
+	if err != nil { return nil }
+	return outs
+}
+
+// openMidiOut opens the output picked in midi-out, if any
+func (s *AppState) openMidiOut() error {
+	name := s.midiOutSel.Selected
+	if name == "" || name == noMidiOut { return nil }
+	for _, out := range s.outPorts() {
+		if out.String() != name { continue }
+		if err := out.Open(); err != nil { return fmt.Errorf("opening MIDI output %s: %v", name, err) }
+		s.mu.Lock(); s.midiOut = out; s.mu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("MIDI output %s not found, refresh the ports", name)
+}
+
+// closeMidiOut closes the MIDI output of the ended session
+func (s *AppState) closeMidiOut() {
+	s.mu.Lock()
+	out := s.midiOut
+	s.midiOut = nil
+	s.mu.Unlock()
+	if out != nil { out.Close() }
+}
+
+// sendSysEx validates a "sysex ..." payload and writes it to the MIDI output
+func (s *AppState) sendSysEx(payload string) error {
+	b, err := parseSysEx(strings.TrimPrefix(payload, sysexPrefix))
+	if err != nil { return err }
+	s.mu.Lock()
+	out := s.midiOut
+	s.mu.Unlock()
+	if out == nil { return fmt.Errorf("sysex: no MIDI output selected in midi-out") }
+	return out.Send(b)
+}
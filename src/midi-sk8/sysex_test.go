@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseSysEx(t *testing.T) {
+	for _, tc := range []struct {
+		text, want, wantErr string
+	}{
+		{"F0 7E 7F 06 01 F7", "f07e7f0601f7", ""},
+		{"f07e7f0601f7", "f07e7f0601f7", ""},
+		{" F0\tF7 ", "f0f7", ""},
+		{"F0 7E 7F 06 01", "", "missing F7"},
+		{"7E 7F F7", "", "start with F0"},
+		{"F0", "", "start with F0"},
+		{"", "", "start with F0"},
+		{"F0 7E 7", "", "odd number"},
+		{"F0 ZZ F7", "", "sysex:"},
+		{"F0 7E 90 F7", "", "byte 2 is 90"},
+	} {
+		got, err := parseSysEx(tc.text)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) { t.Errorf("parseSysEx(%q) error = %v, want %q", tc.text, err, tc.wantErr) }
+			continue
+		}
+		if err != nil || fmt.Sprintf("%x", got) != tc.want { t.Errorf("parseSysEx(%q) = %x, %v, want %s", tc.text, got, err, tc.want) }
+	}
+}
+
+// fakeOut is a MIDI output that keeps what it was sent
+type fakeOut struct{ sent [][]byte }
+
+func (o *fakeOut) Open() error             { return nil }
+func (o *fakeOut) Close() error            { return nil }
+func (o *fakeOut) IsOpen() bool            { return true }
+func (o *fakeOut) Number() int             { return 0 }
+func (o *fakeOut) String() string          { return "fake out" }
+func (o *fakeOut) Underlying() interface{} { return nil }
+func (o *fakeOut) Send(b []byte) error     { o.sent = append(o.sent, b); return nil }
+
+// sysex payloads go to the MIDI output and never onto the network
+func TestSendSysEx(t *testing.T) {
+	s := newAppState()
+	r := testOutput(s)
+	if err := s.send("sysex F0 01 F7"); err == nil || !strings.Contains(err.Error(), "no MIDI output") { t.Errorf("send without midi-out: error = %v", err) }
+	out := &fakeOut{}
+	s.midiOut = out
+	if err := s.send("sysex F0 01 02 F7"); err != nil { t.Fatalf("send: %v", err) }
+	if err := s.send("sysex F0 01 02"); err == nil { t.Error("send of an unterminated sysex did not fail") }
+	if len(out.sent) != 1 || fmt.Sprintf("%x", out.sent[0]) != "f00102f7" { t.Errorf("midi out got %x, want [f00102f7]", out.sent) }
+	if got := r.got(); len(got) != 0 { t.Errorf("network got %q, want nothing", got) }
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// tapKeep is how many recent taps the tempo is averaged over
+const tapKeep = 5
+
+// tapReset is the gap after which a tap starts a new count instead of continuing
+const tapReset = 2 * time.Second
+
+// defaultBPM is the tempo until the first taps
+const defaultBPM = 120
+
+// tapBPM averages the intervals between consecutive taps into beats per minute
+func tapBPM(taps []time.Time) (float64, bool) {
+	if len(taps) < 2 { return 0, false }
+	span := taps[len(taps)-1].Sub(taps[0])
+	if span <= 0 { return 0, false }
+	return float64(len(taps)-1) * float64(time.Minute) / float64(span), true
+}
+
+// tapped records a tap and updates $bpm once there are two taps in a row, returning the tempo
+func (s *AppState) tapped(now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.taps); n > 0 && now.Sub(s.taps[n-1]) > tapReset { s.taps = nil }
+	s.taps = append(s.taps, now)
+	if len(s.taps) > tapKeep { s.taps = s.taps[len(s.taps)-tapKeep:] }
+	if bpm, ok := tapBPM(s.taps); ok { s.bpm = bpm }
+	return s.bpm
+}
+
+// newTapButton builds the tap tempo button, which shows the current tempo
+func (s *AppState) newTapButton() *widget.Button {
+	label := func(bpm float64) string { return fmt.Sprintf("tap %.1f", bpm) }
+	var btn *widget.Button
+	btn = widget.NewButton(label(s.bpm), func() { btn.SetText(label(s.tapped(time.Now()))) })
+	return btn
+}
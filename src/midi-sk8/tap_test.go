@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTapBPM(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms ...int) []time.Time {
+		var taps []time.Time
+		for _, m := range ms { taps = append(taps, t0.Add(time.Duration(m)*time.Millisecond)) }
+		return taps
+	}
+	for _, tc := range []struct {
+		taps []time.Time
+		want float64
+		ok   bool
+	}{
+		{nil, 0, false},
+		{at(0), 0, false},
+		{at(0, 0), 0, false},
+		{at(0, 500), 120, true},
+		{at(0, 500, 1000, 1500), 120, true},
+		{at(0, 400, 1000), 120, true}, // the average interval, not the last one
+		{at(0, 250, 500), 240, true},
+	} {
+		got, ok := tapBPM(tc.taps)
+		if got != tc.want || ok != tc.ok { t.Errorf("tapBPM(%d taps) = %v, %v, want %v, %v", len(tc.taps), got, ok, tc.want, tc.ok) }
+	}
+}
+
+func TestTapped(t *testing.T) {
+	s := newAppState()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := s.tapped(t0); got != defaultBPM { t.Errorf("first tap = %v, want the default %v", got, defaultBPM) }
+	if got := s.tapped(t0.Add(time.Second)); got != 60 { t.Errorf("second tap a second later = %v, want 60", got) }
+	// a long gap starts over and keeps the tempo until the next tap
+	t1 := t0.Add(10 * time.Second)
+	if got := s.tapped(t1); got != 60 { t.Errorf("tap after a pause = %v, want 60", got) }
+	for i := 1; i <= 10; i++ { s.tapped(t1.Add(time.Duration(i) * 500 * time.Millisecond)) }
+	if len(s.taps) != tapKeep { t.Errorf("kept %d taps, want %d", len(s.taps), tapKeep) }
+	if got := s.render("note-on", "$bpm", msgVars(0, 60, 100, 0)); got != "120" { t.Errorf("$bpm = %q, want 120", got) }
+}
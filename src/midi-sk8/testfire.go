@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// testSample is the made-up message a template is test-fired with, rendered the way
+// handle would render it. The values follow the -dryrun sequence.
+func (s *AppState) testSample(key string) (midi.Message, string) {
+	switch key {
+	case "note-on": return midi.NoteOn(0, 60, 100), s.render(key, s.noteOnTpl.Text, s.noteVars(0, 60, 100))
+	case "note-off": return midi.NoteOffVelocity(0, 60, 64), s.render(key, s.noteOffTpl.Text, s.releaseVars(0, 60, 64, 0))
+	case "pitch-bend": return midi.Pitchbend(0, 4096), s.render(key, s.pbTpl.Text, s.bendVars(0, 12288))
+	case "cc":
+		vs := msgVars(0, 7, 0, 0)
+		vs["v"] = 64.0
+		return midi.ControlChange(0, 7, 64), s.render(key, s.ccTpl.Text, vs)
+	case "chan-mode": return midi.ControlChange(0, 123, 0), s.render(key, s.modeTpl.Text, modeVars(0, 123, 0))
+	case "aftertouch": return midi.AfterTouch(0, 90), s.render(key, s.atTpl.Text, s.pressureVars(0, 0, 90))
+	case "poly-at": return midi.PolyAfterTouch(0, 60, 50), s.render(key, s.polyAtTpl.Text, s.pressureVars(0, 60, 50))
+	case "mpe-expr":
+		v := voice{note: 60, vel: 100, held: true, bend: 0.25, pressure: 0.5, slide: 0.5}
+		return midi.Pitchbend(1, 2048), s.render(key, s.mpeTpl.Text, v.addTo(s.noteVars(1, 60, 100)))
+	case "mtc":
+		var m mtcState
+		for _, qf := range dryRunTimecode { m.add(qf) }
+		last := dryRunTimecode[len(dryRunTimecode)-1]
+		return midi.MTC(last), s.render(key, s.mtcTpl.Text, m.vars(last))
+	}
+	return nil, ""
+}
+
+// testFire sends one sample message's payload for template key to the output. It needs
+// no MIDI input, only a connected output.
+func (s *AppState) testFire(key string) {
+	msg, out := s.testSample(key)
+	if out == "" { s.appendLog(s.udpLog, "! test "+key+": template is off or renders nothing"); return }
+	if err := s.sendMsg(out, msg); err != nil { s.appendLog(s.udpLog, "! test "+key+": "+err.Error()); return }
+	s.appendLog(s.udpLog, "> "+out+" (test "+key+")")
+	s.flashOut()
+}
+
+// newTestButton builds the small button beside a template that test-fires it
+func (s *AppState) newTestButton(key string) *widget.Button {
+	return widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() { s.testFire(key) })
+}
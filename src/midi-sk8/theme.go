@@ -0,0 +1,42 @@
+package main
+
+import (
+	"runtime"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// themeModes are the theme choices; auto follows the OS light/dark setting
+var themeModes = []string{"auto", "light", "dark"}
+
+// systemDark reports whether the OS asks for a dark theme. Fyne only reads that setting
+// on macOS, Windows and the Linux/BSD desktops, so everywhere else it is light.
+func systemDark(a fyne.App) bool {
+	switch runtime.GOOS {
+	case "darwin", "windows", "linux", "freebsd", "openbsd", "netbsd":
+		return a.Settings().ThemeVariant() == theme.VariantDark
+	}
+	return false
+}
+
+// applyTheme shows the theme the mode asks for, leaving it alone when already showing.
+// It is also the settings listener, so it must not set a theme it already set.
+func (s *AppState) applyTheme(a fyne.App) {
+	dark := s.themeMode.Selected == "dark" || s.themeMode.Selected == "auto" && systemDark(a)
+	if dark == s.isDark { return }
+	s.isDark = dark
+	if dark { a.Settings().SetTheme(customTheme{theme.DarkTheme()}) } else { a.Settings().SetTheme(customTheme{theme.LightTheme()}) }
+}
+
+// followTheme applies the saved mode and keeps auto in step with the OS from then on
+func (s *AppState) followTheme(a fyne.App) {
+	s.themeMode.OnChanged = func(string) { s.applyTheme(a) }
+	a.Settings().AddListener(func(fyne.Settings) { s.applyTheme(a) })
+	s.applyTheme(a)
+}
+
+// toggleTheme switches to whichever of light or dark is not showing, overriding auto
+func (s *AppState) toggleTheme() {
+	if s.isDark { s.themeMode.SetSelected("light") } else { s.themeMode.SetSelected("dark") }
+}
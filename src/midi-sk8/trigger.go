@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// trigger is a message condition that pauses the log when it matches, like a logic
+// analyser trigger. A negative ch or num matches any; an empty op tests no value.
+type trigger struct {
+	kind    string
+	ch, num int
+	op      string
+	val     int
+}
+
+var triggerTest = regexp.MustCompile(`^([0-9]+)?(=|>=|<=)(-?[0-9]+)$`)
+
+// parseTrigger reads a condition such as "cc ch1 7=127", "note-on 60" or
+// "pitch-bend >=4000": a message kind, then optionally a channel as displayed, a key
+// or controller number and a value test. Empty text is no trigger.
+func parseTrigger(text string) (*trigger, error) {
+	f := strings.Fields(text)
+	if len(f) == 0 { return nil, nil }
+	if !slices.Contains(messageKinds, f[0]) { return nil, fmt.Errorf("trigger: %q is not one of %s", f[0], strings.Join(messageKinds, ", ")) }
+	t := &trigger{kind: f[0], ch: -1, num: -1}
+	for _, tok := range f[1:] {
+		if c, ok := strings.CutPrefix(tok, "ch"); ok {
+			n, err := strconv.Atoi(c)
+			if err != nil || n < 0 || n > 16 { return nil, fmt.Errorf("trigger: %q is not a channel", tok) }
+			t.ch = n
+			continue
+		}
+		if m := triggerTest.FindStringSubmatch(tok); m != nil {
+			if m[1] != "" { t.num, _ = strconv.Atoi(m[1]) }
+			t.op = m[2]
+			t.val, _ = strconv.Atoi(m[3])
+			continue
+		}
+		n, err := strconv.Atoi(tok)
+		if err != nil || n < 0 || n > 127 { return nil, fmt.Errorf("trigger: %q is not a channel (ch1), number (0-127) or value test (=N, >=N, <=N)", tok) }
+		t.num = n
+	}
+	return t, nil
+}
+
+// triggerData is what a trigger tests: key and velocity for notes, key and pressure
+// for poly pressure, controller and value for cc, and just the value for channel
+// pressure and the signed bend. num is -1 where the message has none.
+func triggerData(msg midi.Message) (num, val int, ok bool) {
+	var ch, a, b uint8
+	var bend int16
+	var abs uint16
+	switch {
+	case msg.GetNoteOn(&ch, &a, &b), msg.GetNoteOff(&ch, &a, &b), msg.GetPolyAfterTouch(&ch, &a, &b), msg.GetControlChange(&ch, &a, &b):
+		return int(a), int(b), true
+	case msg.GetAfterTouch(&ch, &b): return -1, int(b), true
+	case msg.GetPitchBend(&ch, &bend, &abs): return -1, int(bend), true
+	}
+	return -1, 0, false
+}
+
+// matches reports whether msg meets the condition, with channels numbered from base
+func (t *trigger) matches(msg midi.Message, base uint8) bool {
+	if messageKind(msg) != t.kind { return false }
+	var ch uint8
+	if t.ch >= 0 && (!msg.GetChannel(&ch) || int(ch)+int(base) != t.ch) { return false }
+	num, val, ok := triggerData(msg)
+	if t.num >= 0 && num != t.num { return false }
+	switch t.op {
+	case "=": return ok && val == t.val
+	case ">=": return ok && val >= t.val
+	case "<=": return ok && val <= t.val
+	}
+	return true
+}
+
+// setTrigger parses the trigger setting for the MIDI goroutine, keeping the last valid one
+func (s *AppState) setTrigger(text string) {
+	t, err := parseTrigger(text)
+	if err != nil { return }
+	s.mu.Lock(); s.trigger = t; s.mu.Unlock()
+}
+
+// checkTrigger logs each message meeting the trigger and, when pausing on trigger is
+// on, pauses the log after it. Nothing is logged while the log is paused.
+func (s *AppState) checkTrigger(msg midi.Message) {
+	if s.isPaused { return }
+	s.mu.Lock()
+	t := s.trigger
+	s.mu.Unlock()
+	if t == nil || !t.matches(msg, s.channelBase()) { return }
+	if !s.autoPause.Checked { s.appendLog(s.midiLog, "! trigger fired on "+msg.String()); return }
+	s.isPaused = true
+	s.appendLog(s.midiLog, "! trigger fired on "+msg.String()+", log paused")
+}
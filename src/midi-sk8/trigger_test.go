@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestParseTrigger(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		want    *trigger
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"note-on", &trigger{kind: "note-on", ch: -1, num: -1}, false},
+		{"note-on 60", &trigger{kind: "note-on", ch: -1, num: 60}, false},
+		{"cc ch1 7=127", &trigger{kind: "cc", ch: 1, num: 7, op: "=", val: 127}, false},
+		{"pitch-bend >=4000", &trigger{kind: "pitch-bend", ch: -1, num: -1, op: ">=", val: 4000}, false},
+		{"pitch-bend <=-100", &trigger{kind: "pitch-bend", ch: -1, num: -1, op: "<=", val: -100}, false},
+		{"sysex", nil, true},
+		{"cc ch17", nil, true},
+		{"cc 128", nil, true},
+		{"cc 7>127", nil, true},
+	} {
+		got, err := parseTrigger(tc.text)
+		if (err != nil) != tc.wantErr { t.Errorf("parseTrigger(%q) error = %v, want error %v", tc.text, err, tc.wantErr); continue }
+		if (got == nil) != (tc.want == nil) || got != nil && *got != *tc.want { t.Errorf("parseTrigger(%q) = %+v, want %+v", tc.text, got, tc.want) }
+	}
+}
+
+func TestTriggerMatches(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		msg  midi.Message
+		base uint8
+		want bool
+	}{
+		{"note-on", midi.NoteOn(3, 60, 100), 1, true},
+		{"note-on", midi.NoteOff(3, 60), 1, false},
+		{"note-on 60", midi.NoteOn(0, 61, 100), 1, false},
+		{"cc ch1 7=127", midi.ControlChange(0, 7, 127), 1, true},
+		{"cc ch1 7=127", midi.ControlChange(0, 7, 126), 1, false},
+		{"cc ch1 7=127", midi.ControlChange(1, 7, 127), 1, false},
+		{"cc ch1 7=127", midi.ControlChange(1, 7, 127), 0, true},
+		{"pitch-bend >=4000", midi.Pitchbend(0, 4000), 1, true},
+		{"pitch-bend >=4000", midi.Pitchbend(0, 3999), 1, false},
+		{"pitch-bend <=-100", midi.Pitchbend(0, -8192), 1, true},
+		{"aftertouch =90", midi.AfterTouch(0, 90), 1, true},
+		{"aftertouch 60", midi.PolyAfterTouch(0, 60, 50), 1, true},
+	} {
+		trig, err := parseTrigger(tc.text)
+		if err != nil { t.Fatal(err) }
+		if got := trig.matches(tc.msg, tc.base); got != tc.want { t.Errorf("%q matches %v (base %d) = %v, want %v", tc.text, tc.msg, tc.base, got, tc.want) }
+	}
+}
+
+// a trigger logs every match; only pausing waits on pause-on-trigger
+func TestCheckTrigger(t *testing.T) {
+	for _, autoPause := range []bool{false, true} {
+		s := newAppState()
+		s.repaint = time.Hour
+		s.autoPause.SetChecked(autoPause)
+		s.setTrigger("cc 7=127")
+		s.checkTrigger(midi.ControlChange(0, 7, 100))
+		s.checkTrigger(midi.ControlChange(0, 7, 127))
+		log := s.logRingFor(s.midiLog).text()
+		if !strings.Contains(log, "! trigger fired on ControlChange") { t.Errorf("pause %v: log %q, want the trigger", autoPause, log) }
+		if strings.Count(log, "\n") != 1 { t.Errorf("pause %v: log %q, want one line", autoPause, log) }
+		if s.isPaused != autoPause { t.Errorf("pause %v: paused = %v", autoPause, s.isPaused) }
+	}
+}
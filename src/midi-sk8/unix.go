@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"runtime"
+	"time"
+)
+
+// outModes are the transports offered in settings; Unix sockets are not offered on Windows
+func outModes() []string {
+	if runtime.GOOS == "windows" { return []string{"udp", "serial", "exec"} }
+	return []string{"udp", "unixgram", "unix", "serial", "exec"}
+}
+
+// dialUnix connects to a receiver's socket, explaining the usual setup mistakes
+func dialUnix(network, path string, timeout time.Duration) (net.Conn, error) {
+	if path == "" { return nil, fmt.Errorf("no socket path set") }
+	fi, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) { return nil, fmt.Errorf("no socket at %s, is the receiver running?", path) }
+	if err != nil { return nil, err }
+	if fi.Mode()&os.ModeSocket == 0 { return nil, fmt.Errorf("%s is not a socket", path) }
+	conn, err := net.DialTimeout(network, path, timeout)
+	if errors.Is(err, fs.ErrPermission) { return nil, fmt.Errorf("permission denied on %s, check the socket's owner and mode", path) }
+	return conn, err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// watchEvery is how often the watched preset is checked for changes
+const watchEvery = 500 * time.Millisecond
+
+// setWatch starts following the preset at path, replacing any earlier watch; an empty
+// path stops watching. It must run on the UI goroutine.
+func (s *AppState) setWatch(path string) {
+	if s.stopWatch != nil { s.stopWatch(); s.stopWatch = nil }
+	path = strings.TrimSpace(path)
+	if path == "" { return }
+	done := make(chan struct{})
+	s.stopWatch = func() { close(done) }
+	go func() {
+		tick := time.NewTicker(watchEvery)
+		defer tick.Stop()
+		var mod time.Time
+		size := int64(-1)
+		for {
+			// a missing file is not logged, since the path may still be being typed
+			if fi, err := os.Stat(path); err == nil && (!fi.ModTime().Equal(mod) || fi.Size() != size) {
+				mod, size = fi.ModTime(), fi.Size()
+				s.reloadPreset(path)
+			}
+			select {
+			case <-done: return
+			case <-tick.C:
+			}
+		}
+	}()
+}
+
+// reloadPreset applies the watched preset without reconnecting; a preset that does not
+// parse or validate is reported and the current templates stay in use
+func (s *AppState) reloadPreset(path string) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var p Preset
+		var warnings []string
+		if p, warnings, err = parsePreset(data); err == nil {
+			fyne.Do(func() {
+				s.applyPreset(p)
+				msg := "Reloaded templates from " + path
+				for _, w := range warnings { msg += "\nwarning: " + w }
+				s.appendLog(s.midiLog, msg)
+			})
+			return
+		}
+	}
+	s.appendLog(s.midiLog, fmt.Sprintf("Error: reloading %s: %v (keeping the current templates)", path, err))
+}